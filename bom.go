@@ -0,0 +1,102 @@
+package productmapper
+
+import "slices"
+
+type LineType string
+
+const (
+	// LineKit is a sellable line whose stock is picked through its LineComponent lines.
+	LineKit LineType = "KIT"
+	// LineComponent is a pick line of a kit, it is not sold on its own.
+	LineComponent LineType = "COMPONENT"
+)
+
+type BOMComponent struct {
+	ProductId string
+	// Qty per kit.
+	Qty int
+	// PriceWeight is the share of the kit price per unit of this component.
+	// When every component of a BOM has PriceWeight 0 the kit price is split by Qty.
+	PriceWeight float64
+}
+
+// BOM lists the stock items a kit ProductId physically consists of.
+type BOM struct {
+	ProductId  string
+	Components []BOMComponent
+}
+
+// ExpandBOM turns every order whose ProductId has a BOM into a LineKit line
+// followed by one LineComponent line per component. The kit keeps its price,
+// the components share it by PriceWeight so their TotalPrice adds up to the
+// kit TotalPrice, and keep its Currency and SourceNos. Orders without a BOM
+// are returned unchanged. When the orders are numbered, No is renumbered from 1.
+func ExpandBOM(orders []CleanedOrder, boms []BOM) ([]CleanedOrder, error) {
+	bomByProductId := map[string]BOM{}
+	for _, bom := range boms {
+		for _, component := range bom.Components {
+			if component.Qty <= 0 {
+				return nil, ErrInvalidQty
+			}
+		}
+		bomByProductId[bom.ProductId] = bom
+	}
+
+	var expanded []CleanedOrder
+	numbered := false
+	for _, order := range orders {
+		numbered = numbered || order.No != 0
+
+		bom, ok := bomByProductId[order.ProductId]
+		if !ok || len(bom.Components) == 0 {
+			expanded = append(expanded, order)
+			continue
+		}
+
+		order.LineType = LineKit
+		expanded = append(expanded, order)
+
+		weights := make([]float64, len(bom.Components))
+		weighted := false
+		for i, component := range bom.Components {
+			weights[i] = component.PriceWeight * float64(component.Qty)
+			weighted = weighted || component.PriceWeight != 0
+		}
+		if !weighted {
+			for i, component := range bom.Components {
+				weights[i] = float64(component.Qty)
+			}
+		}
+
+		prices := allocate(order.TotalPrice, weights)
+		for i, component := range bom.Components {
+			qty, ok := mulQty(order.Qty, component.Qty)
+			if !ok {
+				return nil, ErrQtyOverflow
+			}
+
+			var unitPrice float64
+			if qty != 0 {
+				unitPrice = prices[i] / float64(qty)
+			}
+
+			expanded = append(expanded, CleanedOrder{
+				ProductId:  component.ProductId,
+				Qty:        qty,
+				UnitPrice:  unitPrice,
+				TotalPrice: prices[i],
+				Currency:   order.Currency,
+				LineType:   LineComponent,
+				SourceNos:  slices.Clone(order.SourceNos),
+			})
+		}
+	}
+
+	if numbered {
+		for i := range expanded {
+			expanded[i].No = i + 1
+		}
+	}
+
+	return expanded, nil
+}
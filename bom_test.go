@@ -0,0 +1,213 @@
+package productmapper_test
+
+import (
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandBOM(t *testing.T) {
+	boms := []productmapper.BOM{
+		{
+			ProductId: "FG0A-CLEAR-IPHONE16PROMAX",
+			Components: []productmapper.BOMComponent{
+				{ProductId: "FILM-FG0A-CLEAR-IPHONE16PROMAX", Qty: 1, PriceWeight: 8},
+				{ProductId: "ALIGNMENT-FRAME-IPHONE16PROMAX", Qty: 1, PriceWeight: 1.5},
+				{ProductId: "DUST-STICKER", Qty: 2, PriceWeight: 0.25},
+			},
+		},
+		{
+			ProductId: "FG0A-MATTE-OPPOA3",
+			Components: []productmapper.BOMComponent{
+				{ProductId: "FILM-FG0A-MATTE-OPPOA3", Qty: 1},
+				{ProductId: "DUST-STICKER", Qty: 2},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		orders   []productmapper.CleanedOrder
+		expected []productmapper.CleanedOrder
+		err      error
+	}{
+		{
+			name: "kit split by price weight",
+			orders: []productmapper.CleanedOrder{
+				{
+					ProductId:  "FG0A-CLEAR-IPHONE16PROMAX",
+					MaterialId: "FG0A-CLEAR",
+					ModelId:    "IPHONE16PROMAX",
+					TextureId:  "CLEAR",
+					Qty:        2,
+					UnitPrice:  50,
+					TotalPrice: 100,
+				},
+			},
+			expected: []productmapper.CleanedOrder{
+				{
+					ProductId:  "FG0A-CLEAR-IPHONE16PROMAX",
+					MaterialId: "FG0A-CLEAR",
+					ModelId:    "IPHONE16PROMAX",
+					TextureId:  "CLEAR",
+					Qty:        2,
+					UnitPrice:  50,
+					TotalPrice: 100,
+					LineType:   productmapper.LineKit,
+				},
+				{
+					ProductId:  "FILM-FG0A-CLEAR-IPHONE16PROMAX",
+					Qty:        2,
+					UnitPrice:  40,
+					TotalPrice: 80,
+					LineType:   productmapper.LineComponent,
+				},
+				{
+					ProductId:  "ALIGNMENT-FRAME-IPHONE16PROMAX",
+					Qty:        2,
+					UnitPrice:  7.5,
+					TotalPrice: 15,
+					LineType:   productmapper.LineComponent,
+				},
+				{
+					ProductId:  "DUST-STICKER",
+					Qty:        4,
+					UnitPrice:  1.25,
+					TotalPrice: 5,
+					LineType:   productmapper.LineComponent,
+				},
+			},
+		},
+		{
+			name: "kit without weights split by quantity, line without BOM kept",
+			orders: []productmapper.CleanedOrder{
+				{
+					ProductId:  "FG0A-MATTE-OPPOA3",
+					MaterialId: "FG0A-MATTE",
+					ModelId:    "OPPOA3",
+					TextureId:  "MATTE",
+					Qty:        1,
+					UnitPrice:  100,
+					TotalPrice: 100,
+				},
+				{
+					ProductId:  "FG0A-CLEAR-OPPOA3",
+					MaterialId: "FG0A-CLEAR",
+					ModelId:    "OPPOA3",
+					TextureId:  "CLEAR",
+					Qty:        1,
+					UnitPrice:  40,
+					TotalPrice: 40,
+				},
+			},
+			expected: []productmapper.CleanedOrder{
+				{
+					ProductId:  "FG0A-MATTE-OPPOA3",
+					MaterialId: "FG0A-MATTE",
+					ModelId:    "OPPOA3",
+					TextureId:  "MATTE",
+					Qty:        1,
+					UnitPrice:  100,
+					TotalPrice: 100,
+					LineType:   productmapper.LineKit,
+				},
+				{
+					ProductId:  "FILM-FG0A-MATTE-OPPOA3",
+					Qty:        1,
+					UnitPrice:  33.33,
+					TotalPrice: 33.33,
+					LineType:   productmapper.LineComponent,
+				},
+				{
+					ProductId:  "DUST-STICKER",
+					Qty:        2,
+					UnitPrice:  33.335,
+					TotalPrice: 66.67,
+					LineType:   productmapper.LineComponent,
+				},
+				{
+					ProductId:  "FG0A-CLEAR-OPPOA3",
+					MaterialId: "FG0A-CLEAR",
+					ModelId:    "OPPOA3",
+					TextureId:  "CLEAR",
+					Qty:        1,
+					UnitPrice:  40,
+					TotalPrice: 40,
+				},
+			},
+		},
+	}
+
+	t.Run("numbered lines are renumbered", func(t *testing.T) {
+		orders, err := productmapper.ExpandBOM([]productmapper.CleanedOrder{
+			{No: 1, ProductId: "FG0A-MATTE-OPPOA3", Qty: 1, UnitPrice: 90, TotalPrice: 90},
+			{No: 2, ProductId: "WIPING-CLOTH", Qty: 1},
+		}, boms)
+
+		assert.NoError(t, err)
+		var nos []int
+		for _, order := range orders {
+			nos = append(nos, order.No)
+		}
+		assert.Equal(t, []int{1, 2, 3, 4}, nos)
+		assert.Equal(t, "WIPING-CLOTH", orders[3].ProductId)
+	})
+
+	t.Run("component quantity must be positive", func(t *testing.T) {
+		for _, qty := range []int{0, -2} {
+			_, err := productmapper.ExpandBOM([]productmapper.CleanedOrder{
+				{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1, UnitPrice: 100, TotalPrice: 100},
+			}, []productmapper.BOM{
+				{
+					ProductId: "FG0A-CLEAR-OPPOA3",
+					Components: []productmapper.BOMComponent{
+						{ProductId: "FILM-FG0A-CLEAR-OPPOA3", Qty: 1},
+						{ProductId: "DUST-STICKER", Qty: qty},
+					},
+				},
+			})
+			assert.ErrorIs(t, err, productmapper.ErrInvalidQty, "component qty %d", qty)
+		}
+	})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			orders, err := productmapper.ExpandBOM(test.orders, boms)
+
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, orders)
+		})
+	}
+
+	t.Run("components do not add complementary items", func(t *testing.T) {
+		orders, err := productmapper.ExpandBOM([]productmapper.CleanedOrder{
+			{
+				ProductId:  "FG0A-MATTE-OPPOA3",
+				MaterialId: "FG0A-MATTE",
+				ModelId:    "OPPOA3",
+				TextureId:  "MATTE",
+				Qty:        1,
+				UnitPrice:  100,
+				TotalPrice: 100,
+			},
+		}, boms)
+		assert.NoError(t, err)
+
+		orders = productmapper.WithComplementary(orders, []productmapper.ComplementaryItem{
+			{
+				ProductId: "WIPING-CLOTH",
+				PerQty:    1,
+			},
+		})
+
+		assert.Len(t, orders, 4)
+		assert.Equal(t, productmapper.CleanedOrder{No: 4, ProductId: "WIPING-CLOTH", Qty: 1}, orders[3])
+		assert.True(t, orders[0].IsSellable())
+		assert.False(t, orders[0].IsPick())
+		assert.False(t, orders[1].IsSellable())
+		assert.True(t, orders[1].IsPick())
+		assert.True(t, orders[3].IsSellable())
+		assert.True(t, orders[3].IsPick())
+	})
+}
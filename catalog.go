@@ -0,0 +1,186 @@
+package productmapper
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Catalog is the SKU master that cleaned product ids and material ids are checked against.
+type Catalog interface {
+	HasProduct(ctx context.Context, productId string) (bool, error)
+	HasMaterial(ctx context.Context, materialId string) (bool, error)
+}
+
+// MemoryCatalog is a Catalog held in memory.
+type MemoryCatalog struct {
+	products  map[string]struct{}
+	materials map[string]struct{}
+}
+
+func NewMemoryCatalog() *MemoryCatalog {
+	return &MemoryCatalog{
+		products:  map[string]struct{}{},
+		materials: map[string]struct{}{},
+	}
+}
+
+// AddProduct registers productId and, when not empty, its materialId.
+func (c *MemoryCatalog) AddProduct(productId string, materialId string) {
+	c.products[productId] = struct{}{}
+	if materialId != "" {
+		c.materials[materialId] = struct{}{}
+	}
+}
+
+func (c *MemoryCatalog) HasProduct(_ context.Context, productId string) (bool, error) {
+	_, ok := c.products[productId]
+	return ok, nil
+}
+
+func (c *MemoryCatalog) HasMaterial(_ context.Context, materialId string) (bool, error) {
+	_, ok := c.materials[materialId]
+	return ok, nil
+}
+
+var ErrInvalidCatalog = errors.New("invalid catalog")
+
+var catalogHeader = []string{"product_id", "material_id"}
+
+// LoadCatalog reads a CSV SKU master with the header
+//
+//	product_id,material_id
+//
+// material_id is empty for items that are not films, e.g. complementary items.
+func LoadCatalog(r io.Reader) (*MemoryCatalog, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCatalog, err)
+	}
+	if len(records) == 0 || !slices.Equal(records[0], catalogHeader) {
+		return nil, fmt.Errorf("%w: missing header %s", ErrInvalidCatalog, strings.Join(catalogHeader, ","))
+	}
+
+	catalog := NewMemoryCatalog()
+	for i, record := range records[1:] {
+		if record[0] == "" {
+			return nil, fmt.Errorf("%w: line %d: empty product id", ErrInvalidCatalog, i+2)
+		}
+		catalog.AddProduct(record[0], record[1])
+	}
+
+	return catalog, nil
+}
+
+// CSVCatalog is a Catalog backed by a CSV file in the LoadCatalog format,
+// the file is read on first use.
+type CSVCatalog struct {
+	Path string
+
+	once    sync.Once
+	catalog *MemoryCatalog
+	err     error
+}
+
+func NewCSVCatalog(path string) *CSVCatalog {
+	return &CSVCatalog{Path: path}
+}
+
+func (c *CSVCatalog) load() (*MemoryCatalog, error) {
+	c.once.Do(func() {
+		f, err := os.Open(c.Path)
+		if err != nil {
+			c.err = err
+			return
+		}
+		defer f.Close()
+
+		c.catalog, c.err = LoadCatalog(f)
+	})
+	return c.catalog, c.err
+}
+
+func (c *CSVCatalog) HasProduct(ctx context.Context, productId string) (bool, error) {
+	catalog, err := c.load()
+	if err != nil {
+		return false, err
+	}
+	return catalog.HasProduct(ctx, productId)
+}
+
+func (c *CSVCatalog) HasMaterial(ctx context.Context, materialId string) (bool, error) {
+	catalog, err := c.load()
+	if err != nil {
+		return false, err
+	}
+	return catalog.HasMaterial(ctx, materialId)
+}
+
+// CatalogIssue is a cleaned line whose product id or material id is not in the Catalog.
+type CatalogIssue struct {
+	No        int
+	ProductId string
+	// Field is "ProductId" or "MaterialId".
+	Field string
+	Value string
+}
+
+func (i CatalogIssue) String() string {
+	return fmt.Sprintf("order %d: %s '%s' not found in catalog", i.No, i.Field, i.Value)
+}
+
+var ErrNotInCatalog = errors.New("not found in catalog")
+
+// CheckCatalog checks the product id and material id of every order,
+// complementary items included, against catalog. Missing ids are returned
+// as issues in ModeLenient and as an ErrNotInCatalog error in ModeStrict.
+func CheckCatalog(ctx context.Context, catalog Catalog, orders []CleanedOrder, mode Mode) ([]CatalogIssue, error) {
+	var issues []CatalogIssue
+
+	for _, order := range orders {
+		ok, err := catalog.HasProduct(ctx, order.ProductId)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			issues = append(issues, CatalogIssue{
+				No:        order.No,
+				ProductId: order.ProductId,
+				Field:     "ProductId",
+				Value:     order.ProductId,
+			})
+		}
+
+		if order.MaterialId == "" {
+			continue
+		}
+		ok, err = catalog.HasMaterial(ctx, order.MaterialId)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			issues = append(issues, CatalogIssue{
+				No:        order.No,
+				ProductId: order.ProductId,
+				Field:     "MaterialId",
+				Value:     order.MaterialId,
+			})
+		}
+	}
+
+	if mode == ModeStrict && len(issues) > 0 {
+		return issues, fmt.Errorf("%w: %s", ErrNotInCatalog, issues[0])
+	}
+
+	return issues, nil
+}
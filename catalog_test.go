@@ -0,0 +1,131 @@
+package productmapper_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+const catalogCSV = `product_id,material_id
+FG0A-CLEAR-OPPOA3,FG0A-CLEAR
+FG0A-MATTE-OPPOA3,FG0A-MATTE
+WIPING-CLOTH,
+CLEAR-CLEANNER,
+`
+
+func TestCheckCatalog(t *testing.T) {
+	catalog, err := productmapper.LoadCatalog(strings.NewReader(catalogCSV))
+	assert.NoError(t, err)
+
+	orders, err := productmapper.CleanOrder(context.Background(), []productmapper.InputOrder{
+		{
+			No:                1,
+			PlatformProductId: "FG0A-CLEAR-OPPOA3/FG0A-PRIVACY-OPPOA3",
+			Qty:               1,
+			UnitPrice:         80,
+			TotalPrice:        80,
+		},
+	}, []productmapper.ComplementaryItem{
+		{
+			ProductId: "WIPING-CLOTH",
+			PerQty:    1,
+		},
+		{
+			ProductId: "CLEANNER",
+			PerQty:    1,
+			Type:      "SUFFIX_TEXTURE",
+		},
+	})
+	assert.NoError(t, err)
+
+	expectedIssues := []productmapper.CatalogIssue{
+		{
+			No:        2,
+			ProductId: "FG0A-PRIVACY-OPPOA3",
+			Field:     "ProductId",
+			Value:     "FG0A-PRIVACY-OPPOA3",
+		},
+		{
+			No:        2,
+			ProductId: "FG0A-PRIVACY-OPPOA3",
+			Field:     "MaterialId",
+			Value:     "FG0A-PRIVACY",
+		},
+		{
+			No:        5,
+			ProductId: "PRIVACY-CLEANNER",
+			Field:     "ProductId",
+			Value:     "PRIVACY-CLEANNER",
+		},
+	}
+
+	t.Run("lenient mode flags missing ids", func(t *testing.T) {
+		issues, err := productmapper.CheckCatalog(context.Background(), catalog, orders, productmapper.ModeLenient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expectedIssues, issues)
+	})
+
+	t.Run("strict mode rejects missing ids", func(t *testing.T) {
+		issues, err := productmapper.CheckCatalog(context.Background(), catalog, orders, productmapper.ModeStrict)
+
+		assert.ErrorIs(t, err, productmapper.ErrNotInCatalog)
+		assert.EqualError(t, err, "not found in catalog: order 2: ProductId 'FG0A-PRIVACY-OPPOA3' not found in catalog")
+		assert.Equal(t, expectedIssues, issues)
+	})
+
+	t.Run("every id in catalog", func(t *testing.T) {
+		issues, err := productmapper.CheckCatalog(context.Background(), catalog, orders[:1], productmapper.ModeStrict)
+
+		assert.NoError(t, err)
+		assert.Empty(t, issues)
+	})
+}
+
+func TestCSVCatalog(t *testing.T) {
+	t.Run("loaded from file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "catalog.csv")
+		assert.NoError(t, os.WriteFile(path, []byte(catalogCSV), 0o644))
+
+		catalog := productmapper.NewCSVCatalog(path)
+
+		ok, err := catalog.HasProduct(context.Background(), "WIPING-CLOTH")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = catalog.HasMaterial(context.Background(), "FG0A-MATTE")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = catalog.HasMaterial(context.Background(), "WIPING-CLOTH")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		catalog := productmapper.NewCSVCatalog(filepath.Join(t.TempDir(), "missing.csv"))
+
+		_, err := catalog.HasProduct(context.Background(), "WIPING-CLOTH")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		_, err = productmapper.CheckCatalog(context.Background(), catalog, []productmapper.CleanedOrder{{No: 1, ProductId: "WIPING-CLOTH"}}, productmapper.ModeLenient)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		_, err := productmapper.LoadCatalog(strings.NewReader("product_id,material_id\nFG0A-CLEAR-OPPOA3\n"))
+
+		assert.ErrorIs(t, err, productmapper.ErrInvalidCatalog)
+	})
+
+	t.Run("missing header", func(t *testing.T) {
+		_, err := productmapper.LoadCatalog(strings.NewReader("FG0A-CLEAR-OPPOA3,FG0A-CLEAR\nWIPING-CLOTH,\n"))
+
+		assert.ErrorIs(t, err, productmapper.ErrInvalidCatalog)
+	})
+}
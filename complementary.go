@@ -1,6 +1,11 @@
 package productmapper
 
-import "github.com/elliotchance/orderedmap/v3"
+import (
+	"context"
+	"log/slog"
+
+	"github.com/elliotchance/orderedmap/v3"
+)
 
 type ComplementaryItem struct {
 	ProductId string
@@ -9,7 +14,40 @@ type ComplementaryItem struct {
 	Type string // SUFFIX_TEXTURE
 }
 
+const ComplementarySuffixTexture = "SUFFIX_TEXTURE"
+
+// ComplementaryStrategy returns the product id of the complementary item
+// given with order, for a logical ComplementaryItem.Type.
+type ComplementaryStrategy func(order CleanedOrder, item ComplementaryItem) string
+
+// DefaultComplementaryStrategies returns the built-in strategies by
+// ComplementaryItem.Type. Items of any other type are given as their ProductId.
+func DefaultComplementaryStrategies() map[string]ComplementaryStrategy {
+	return map[string]ComplementaryStrategy{
+		ComplementarySuffixTexture: func(order CleanedOrder, item ComplementaryItem) string {
+			return order.TextureId + "-" + item.ProductId
+		},
+	}
+}
+
+// WithComplementary numbers orders and appends the complementary items given
+// with them. It returns nil when a quantity overflows, see
+// WithComplementaryChecked for the error.
 func WithComplementary(orders []CleanedOrder, complementaryItems []ComplementaryItem) []CleanedOrder {
+	withItems, err := WithComplementaryChecked(orders, complementaryItems)
+	if err != nil {
+		return nil
+	}
+	return withItems
+}
+
+// WithComplementaryChecked is WithComplementary returning an ErrQtyOverflow
+// when a complementary quantity overflows.
+func WithComplementaryChecked(orders []CleanedOrder, complementaryItems []ComplementaryItem) ([]CleanedOrder, error) {
+	return withComplementary(context.Background(), nil, noopMetrics{}, orders, complementaryItems, DefaultComplementaryStrategies())
+}
+
+func withComplementary(ctx context.Context, logger *slog.Logger, metrics Metrics, orders []CleanedOrder, complementaryItems []ComplementaryItem, strategies map[string]ComplementaryStrategy) ([]CleanedOrder, error) {
 	newOrders := []CleanedOrder{}
 	omapComplementary := orderedmap.NewOrderedMap[string, int]()
 
@@ -19,17 +57,37 @@ func WithComplementary(orders []CleanedOrder, complementaryItems []Complementary
 		orderNo++
 		newOrders = append(newOrders, order)
 
+		if !order.IsSellable() {
+			// the kit line already counted for the complementary items
+			continue
+		}
+
 		for _, complementaryItem := range complementaryItems {
-			switch complementaryItem.Type { // implement more type later
-			case "SUFFIX_TEXTURE":
-				key := order.TextureId + "-" + complementaryItem.ProductId
-				currentQty, _ := omapComplementary.Get(key)
-				omapComplementary.Set(key, currentQty+order.Qty*complementaryItem.PerQty)
-			default:
-				key := complementaryItem.ProductId
-				currentQty, _ := omapComplementary.Get(key)
-				omapComplementary.Set(key, currentQty+order.Qty*complementaryItem.PerQty)
+			key := complementaryItem.ProductId
+			if strategy, ok := strategies[complementaryItem.Type]; ok {
+				key = strategy(order, complementaryItem)
 			}
+
+			lineQty, ok := mulQty(order.Qty, complementaryItem.PerQty)
+			if !ok {
+				return nil, ErrQtyOverflow
+			}
+			currentQty, _ := omapComplementary.Get(key)
+			qty, ok := addQty(currentQty, lineQty)
+			if !ok {
+				return nil, ErrQtyOverflow
+			}
+			omapComplementary.Set(key, qty)
+			metrics.ComplementaryItems(ComplementaryRule(complementaryItem), lineQty)
+
+			logAttrs(ctx, logger, slog.LevelDebug, "complementary item",
+				slog.Int("line_no", order.No),
+				slog.String("line_product_id", order.ProductId),
+				slog.String("rule_product_id", complementaryItem.ProductId),
+				slog.String("rule_type", complementaryItem.Type),
+				slog.String("product_id", key),
+				slog.Int("qty", lineQty),
+			)
 		}
 	}
 
@@ -42,5 +100,5 @@ func WithComplementary(orders []CleanedOrder, complementaryItems []Complementary
 		orderNo++
 	}
 
-	return newOrders
+	return newOrders, nil
 }
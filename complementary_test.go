@@ -1,6 +1,7 @@
 package productmapper_test
 
 import (
+	"math"
 	"testing"
 
 	"github.com/Kritsana135/productmapper"
@@ -25,6 +26,7 @@ func TestWithComplementary(t *testing.T) {
 		orders             []productmapper.CleanedOrder
 		complementaryItems []productmapper.ComplementaryItem
 		expected           []productmapper.CleanedOrder
+		err                error
 	}{
 		{
 			name: "one order with two qty",
@@ -246,12 +248,33 @@ func TestWithComplementary(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "complementary quantity overflows",
+			orders: []productmapper.CleanedOrder{
+				{
+					ProductId:  "FG0A-CLEAR-IPHONE16PROMAX",
+					MaterialId: "FG0A-CLEAR",
+					ModelId:    "IPHONE16PROMAX",
+					TextureId:  "CLEAR",
+					Qty:        math.MaxInt / 2,
+				},
+			},
+			complementaryItems: []productmapper.ComplementaryItem{
+				{
+					ProductId: "WIPING-CLOTH",
+					PerQty:    3,
+				},
+			},
+			err: productmapper.ErrQtyOverflow,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			orders := productmapper.WithComplementary(test.orders, test.complementaryItems)
+			orders, err := productmapper.WithComplementaryChecked(test.orders, test.complementaryItems)
+			assert.Equal(t, test.err, err)
 			assert.Equal(t, test.expected, orders)
+			assert.Equal(t, test.expected, productmapper.WithComplementary(test.orders, test.complementaryItems))
 		})
 	}
 }
@@ -0,0 +1,168 @@
+package productmapper
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	CurrencyTHB = "THB"
+	CurrencyMYR = "MYR"
+	CurrencySGD = "SGD"
+)
+
+// RateProvider returns how many units of currency to one unit of currency from is worth.
+type RateProvider interface {
+	Rate(ctx context.Context, from string, to string) (float64, error)
+}
+
+var (
+	ErrNoRate       = errors.New("no exchange rate")
+	ErrInvalidRates = errors.New("invalid exchange rates")
+)
+
+// StaticRates is a RateProvider with a fixed table of rates. A rate also
+// converts back at its inverse unless that direction is set on its own.
+type StaticRates struct {
+	rates map[[2]string]float64
+}
+
+func NewStaticRates() *StaticRates {
+	return &StaticRates{rates: map[[2]string]float64{}}
+}
+
+// Set registers that one unit of from is worth rate units of to.
+func (r *StaticRates) Set(from string, to string, rate float64) error {
+	if rate <= 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return fmt.Errorf("%w: %v for %s/%s", ErrInvalidRates, rate, from, to)
+	}
+	r.rates[[2]string{normalizeCurrency(from), normalizeCurrency(to)}] = rate
+	return nil
+}
+
+func (r *StaticRates) Rate(_ context.Context, from string, to string) (float64, error) {
+	from, to = normalizeCurrency(from), normalizeCurrency(to)
+	if from == to {
+		return 1, nil
+	}
+	if rate, ok := r.rates[[2]string{from, to}]; ok {
+		return rate, nil
+	}
+	if rate, ok := r.rates[[2]string{to, from}]; ok {
+		return 1 / rate, nil
+	}
+	return 0, fmt.Errorf("%w: %s/%s", ErrNoRate, from, to)
+}
+
+var ratesHeader = []string{"from", "to", "rate"}
+
+// LoadRates reads CSV exchange rates with the header
+//
+//	from,to,rate
+func LoadRates(r io.Reader) (*StaticRates, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRates, err)
+	}
+	if len(records) == 0 || !slices.Equal(records[0], ratesHeader) {
+		return nil, fmt.Errorf("%w: missing header %s", ErrInvalidRates, strings.Join(ratesHeader, ","))
+	}
+
+	rates := NewStaticRates()
+	for i, record := range records[1:] {
+		if record[0] == "" || record[1] == "" {
+			return nil, fmt.Errorf("%w: line %d: empty currency", ErrInvalidRates, i+2)
+		}
+		rate, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidRates, i+2, err)
+		}
+		if err := rates.Set(record[0], record[1], rate); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+2, err)
+		}
+	}
+
+	return rates, nil
+}
+
+// FileRates is a RateProvider backed by a CSV file in the LoadRates format
+// for offline use, the file is read on first use.
+type FileRates struct {
+	Path string
+
+	once  sync.Once
+	rates *StaticRates
+	err   error
+}
+
+func NewFileRates(path string) *FileRates {
+	return &FileRates{Path: path}
+}
+
+func (r *FileRates) Rate(ctx context.Context, from string, to string) (float64, error) {
+	r.once.Do(func() {
+		f, err := os.Open(r.Path)
+		if err != nil {
+			r.err = err
+			return
+		}
+		defer f.Close()
+
+		r.rates, r.err = LoadRates(f)
+	})
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.rates.Rate(ctx, from, to)
+}
+
+// ConvertCurrency sets ReportingTotalPrice of every line to its TotalPrice in
+// reportingCurrency rounded to 2 decimals, and ReportingUnitPrice to
+// ReportingTotalPrice / Qty, not rounded. Only the prices are converted,
+// UnitPrice, TotalPrice, Discount, Fees and the tax amounts stay in the line
+// Currency. Lines without a Currency are taken to be in reportingCurrency.
+func ConvertCurrency(ctx context.Context, orders []CleanedOrder, rates RateProvider, reportingCurrency string) ([]CleanedOrder, error) {
+	reportingCurrency = normalizeCurrency(reportingCurrency)
+
+	converted := make([]CleanedOrder, len(orders))
+	copy(converted, orders)
+
+	for i := range converted {
+		line := &converted[i]
+
+		rate := 1.0
+		if line.Currency != "" {
+			var err error
+			rate, err = rates.Rate(ctx, line.Currency, reportingCurrency)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		line.ReportingCurrency = reportingCurrency
+		line.ExchangeRate = rate
+		line.ReportingTotalPrice = roundMoney(line.TotalPrice * rate)
+		if line.Qty > 0 {
+			line.ReportingUnitPrice = line.ReportingTotalPrice / float64(line.Qty)
+		}
+	}
+
+	return converted, nil
+}
+
+func normalizeCurrency(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
@@ -0,0 +1,185 @@
+package productmapper_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+const ratesCSV = `from,to,rate
+MYR,THB,7.5
+SGD,THB,25.2
+`
+
+func TestConvertCurrency(t *testing.T) {
+	rates, err := productmapper.LoadRates(strings.NewReader(ratesCSV))
+	assert.NoError(t, err)
+
+	orders, err := productmapper.CleanOrder(context.Background(), []productmapper.InputOrder{
+		{
+			No:                1,
+			PlatformProductId: "FG0A-CLEAR-OPPOA3",
+			Qty:               3,
+			UnitPrice:         10,
+			TotalPrice:        30,
+			Currency:          productmapper.CurrencyMYR,
+		},
+		{
+			No:                2,
+			PlatformProductId: "FG0A-MATTE-OPPOA3",
+			Qty:               1,
+			UnitPrice:         100,
+			TotalPrice:        100,
+			Currency:          productmapper.CurrencyTHB,
+		},
+	}, []productmapper.ComplementaryItem{
+		{
+			ProductId: "WIPING-CLOTH",
+			PerQty:    1,
+		},
+	})
+	assert.NoError(t, err)
+
+	type amounts struct {
+		currency           string
+		totalPrice         float64
+		reportingCurrency  string
+		rate               float64
+		reportingUnitPrice float64
+		reportingTotal     float64
+	}
+
+	tests := []struct {
+		name              string
+		reportingCurrency string
+		expected          []amounts
+		err               error
+	}{
+		{
+			name:              "to THB",
+			reportingCurrency: productmapper.CurrencyTHB,
+			expected: []amounts{
+				{currency: "MYR", totalPrice: 30, reportingCurrency: "THB", rate: 7.5, reportingUnitPrice: 75, reportingTotal: 225},
+				{currency: "THB", totalPrice: 100, reportingCurrency: "THB", rate: 1, reportingUnitPrice: 100, reportingTotal: 100},
+				{reportingCurrency: "THB", rate: 1},
+			},
+		},
+		{
+			name:              "to MYR with the inverse rate",
+			reportingCurrency: "myr",
+			expected: []amounts{
+				{currency: "MYR", totalPrice: 30, reportingCurrency: "MYR", rate: 1, reportingUnitPrice: 10, reportingTotal: 30},
+				{currency: "THB", totalPrice: 100, reportingCurrency: "MYR", rate: 1 / 7.5, reportingUnitPrice: 13.33, reportingTotal: 13.33},
+				{reportingCurrency: "MYR", rate: 1},
+			},
+		},
+		{
+			name:              "missing rate",
+			reportingCurrency: "USD",
+			err:               productmapper.ErrNoRate,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			converted, err := productmapper.ConvertCurrency(context.Background(), orders, rates, test.reportingCurrency)
+
+			assert.ErrorIs(t, err, test.err)
+			if test.err != nil {
+				return
+			}
+
+			var actual []amounts
+			for _, order := range converted {
+				actual = append(actual, amounts{
+					currency:           order.Currency,
+					totalPrice:         order.TotalPrice,
+					reportingCurrency:  order.ReportingCurrency,
+					rate:               order.ExchangeRate,
+					reportingUnitPrice: order.ReportingUnitPrice,
+					reportingTotal:     order.ReportingTotalPrice,
+				})
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestConvertCurrencyOnlyPrices(t *testing.T) {
+	rates, err := productmapper.LoadRates(strings.NewReader(ratesCSV))
+	assert.NoError(t, err)
+
+	converted, err := productmapper.ConvertCurrency(context.Background(), []productmapper.CleanedOrder{
+		{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 7, UnitPrice: 10.0 / 7, TotalPrice: 10, Discount: 1, Currency: "MYR"},
+	}, rates, "THB")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 75.0, converted[0].ReportingTotalPrice)
+	assert.Equal(t, 75.0/7, converted[0].ReportingUnitPrice, "the unit price is not rounded")
+	assert.Equal(t, 1.0, converted[0].Discount, "the discount stays in the line currency")
+}
+
+func TestConvertCurrencyExpandedKit(t *testing.T) {
+	rates, err := productmapper.LoadRates(strings.NewReader(ratesCSV))
+	assert.NoError(t, err)
+
+	lines, err := productmapper.ExpandBOM([]productmapper.CleanedOrder{
+		{ProductId: "FG0A-CLEAR-KIT", Qty: 1, UnitPrice: 20, TotalPrice: 20, Currency: "MYR", SourceNos: []int{1}},
+	}, []productmapper.BOM{
+		{
+			ProductId: "FG0A-CLEAR-KIT",
+			Components: []productmapper.BOMComponent{
+				{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1, PriceWeight: 3},
+				{ProductId: "DUST-STICKER", Qty: 1, PriceWeight: 1},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	converted, err := productmapper.ConvertCurrency(context.Background(), lines, rates, "THB")
+
+	assert.NoError(t, err)
+	var reportingTotalPrices []float64
+	for _, line := range converted {
+		assert.Equal(t, "MYR", line.Currency, line.ProductId)
+		assert.Equal(t, 7.5, line.ExchangeRate, line.ProductId)
+		assert.Equal(t, []int{1}, line.SourceNos, line.ProductId)
+		reportingTotalPrices = append(reportingTotalPrices, line.ReportingTotalPrice)
+	}
+	assert.Equal(t, []float64{150, 112.5, 37.5}, reportingTotalPrices)
+}
+
+func TestLoadRates(t *testing.T) {
+	t.Run("invalid rate", func(t *testing.T) {
+		_, err := productmapper.LoadRates(strings.NewReader("from,to,rate\nMYR,THB,-1\n"))
+
+		assert.ErrorIs(t, err, productmapper.ErrInvalidRates)
+	})
+
+	t.Run("missing header", func(t *testing.T) {
+		_, err := productmapper.LoadRates(strings.NewReader("MYR,THB,7.5\nSGD,THB,25\n"))
+
+		assert.ErrorIs(t, err, productmapper.ErrInvalidRates)
+	})
+
+	t.Run("file rates", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rates.csv")
+		assert.NoError(t, os.WriteFile(path, []byte(ratesCSV), 0o644))
+
+		rate, err := productmapper.NewFileRates(path).Rate(context.Background(), "SGD", "THB")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 25.2, rate)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := productmapper.NewFileRates(filepath.Join(t.TempDir(), "rates.csv")).Rate(context.Background(), "SGD", "THB")
+
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
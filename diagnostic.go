@@ -0,0 +1,36 @@
+package productmapper
+
+import "strconv"
+
+type DiagnosticCode string
+
+const (
+	DiagnosticUnknownTexture  DiagnosticCode = "UNKNOWN_TEXTURE"
+	DiagnosticUnknownModel    DiagnosticCode = "UNKNOWN_MODEL"
+	DiagnosticUnknownFilmType DiagnosticCode = "UNKNOWN_FILM_TYPE"
+	DiagnosticPriceSurcharge  DiagnosticCode = "PRICE_SURCHARGE"
+	DiagnosticPriceBelowFloor DiagnosticCode = "PRICE_BELOW_FLOOR"
+)
+
+// Diagnostic is a problem found while parsing or diffusing that was not fatal.
+type Diagnostic struct {
+	Code    DiagnosticCode
+	Message string
+	Input   string
+	Index   int
+	// Value is the offending id, e.g. the unknown film type, texture or model,
+	// the surcharge amount or the product priced below its floor.
+	Value string
+	// Suggestions are the closest known ids to Value, closest first.
+	Suggestions []string
+}
+
+func (d Diagnostic) String() string {
+	if d.Input == "" {
+		return string(d.Code) + ": " + d.Message
+	}
+	if d.Index != 0 {
+		return string(d.Code) + ": " + d.Message + " at index " + strconv.Itoa(d.Index) + " in '" + d.Input + "'"
+	}
+	return string(d.Code) + ": " + d.Message + " in '" + d.Input + "'"
+}
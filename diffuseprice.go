@@ -1,37 +1,376 @@
 package productmapper
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+)
 
 type LineItemDetail struct {
 	Qty        int
 	UnitPrice  float64
 	TotalPrice float64
+	// FreeProducts are the ProductIds or MaterialIds of the bundle members
+	// given away for free on this line, e.g. a sponsored gift film. They get
+	// a zero price and the other members take the full line price. When every
+	// member is free the marks are ignored so the price is not lost.
+	FreeProducts []string
 }
 
-var ErrInvalidUnitPrice = errors.New("invalid unit price")
+var (
+	ErrInvalidUnitPrice = errors.New("invalid unit price")
+	ErrQtyOverflow      = errors.New("quantity overflow")
+	ErrInvalidQty       = errors.New("invalid quantity")
+	ErrQtyExceeded      = errors.New("quantity exceeds maximum")
+)
+
+// DefaultMaxQty is the largest diffused quantity of a line a Diffuser from
+// NewDiffuser accepts.
+const DefaultMaxQty = 100000
+
+// PriceSource is the price of a line item that is diffused when UnitPrice * Qty
+// and TotalPrice disagree within the tolerance.
+type PriceSource int
+
+const (
+	// TrustTotalPrice diffuses TotalPrice.
+	TrustTotalPrice PriceSource = iota
+	// TrustUnitPrice diffuses UnitPrice * Qty.
+	TrustUnitPrice
+)
+
+// Diffuser splits the price of a marketplace line over its products.
+type Diffuser struct {
+	// AbsTolerance and RelTolerance are how much UnitPrice * Qty and TotalPrice
+	// may differ, as an amount and as a fraction of TotalPrice. The larger of
+	// the two applies. A UnitPrice * Qty above TotalPrice by more than the
+	// tolerance is an ErrInvalidUnitPrice, a TotalPrice above UnitPrice * Qty
+	// by more than the tolerance, e.g. a surcharge, is a DiagnosticPriceSurcharge.
+	AbsTolerance float64
+	RelTolerance float64
+	// UnitTolerance is how much UnitPrice * Qty and TotalPrice may differ per
+	// unit of the line, e.g. 0.01 accepts a UnitPrice rounded up or down to
+	// the satang such as 33.34 * 3 for 100.00. The largest of the tolerances
+	// applies.
+	UnitTolerance float64
+	Trust         PriceSource
+	// Floors are the minimum unit prices of products by ProductId or
+	// MaterialId, the ProductId taking precedence. A product priced below its
+	// floor is raised to it and the shortfall is taken from the other paid
+	// products. When the line price cannot cover every floor the products are
+	// priced in proportion to their floors and a DiagnosticPriceBelowFloor is
+	// reported for every product below its floor.
+	Floors map[string]float64
+	// MaxQty limits the diffused quantity of a line, the quantity of the
+	// platform product id times the line quantity, 0 means no limit.
+	MaxQty int
+	// Explain records a PriceTrace on every diffused line.
+	Explain bool
+	// Logger logs price adjustments at info level and price problems at warn
+	// level, nil disables logging.
+	Logger *slog.Logger
+}
+
+// NewDiffuser returns a Diffuser that trusts TotalPrice, accepts lines of up
+// to DefaultMaxQty and a UnitPrice rounded to the satang. The unit tolerance
+// is a satang rather than half of one because marketplaces also round unit
+// prices up, 33.34 * 3 is 0.02 above 100.00.
+func NewDiffuser() *Diffuser {
+	return &Diffuser{UnitTolerance: 0.01, Trust: TrustTotalPrice, MaxQty: DefaultMaxQty}
+}
+
+type DiffuseResult struct {
+	Orders []CleanedOrder
+	// Diagnostics are price mismatches that did not stop the diffusion.
+	Diagnostics []Diagnostic
+}
 
 func DiffusePrice(productParts []ProductParts, totalQty int, lineItemDetail LineItemDetail) ([]CleanedOrder, error) {
-	if (lineItemDetail.UnitPrice * float64(lineItemDetail.Qty)) > lineItemDetail.TotalPrice {
-		return nil, ErrInvalidUnitPrice
+	result, err := NewDiffuser().Diffuse(productParts, totalQty, lineItemDetail)
+	if err != nil {
+		return nil, err
+	}
+	return result.Orders, nil
+}
+
+func (d *Diffuser) Diffuse(productParts []ProductParts, totalQty int, lineItemDetail LineItemDetail) (*DiffuseResult, error) {
+	return d.diffuse(context.Background(), d.Logger, productParts, totalQty, lineItemDetail)
+}
+
+func (d *Diffuser) diffuse(ctx context.Context, logger *slog.Logger, productParts []ProductParts, totalQty int, lineItemDetail LineItemDetail) (*DiffuseResult, error) {
+	lineTotal, diagnostics, err := d.lineTotal(lineItemDetail)
+	if err != nil {
+		return nil, err
+	}
+	for _, diagnostic := range diagnostics {
+		logAttrs(ctx, logger, slog.LevelWarn, diagnostic.Message, slog.String("code", string(diagnostic.Code)), slog.String("value", diagnostic.Value))
+	}
+	if lineTotal != lineItemDetail.TotalPrice {
+		logAttrs(ctx, logger, slog.LevelInfo, "line total taken from unit price",
+			slog.Float64("total_price", lineItemDetail.TotalPrice),
+			slog.Float64("allocated_total", lineTotal),
+		)
+	}
+
+	if lineItemDetail.Qty < 0 || totalQty < 0 {
+		return nil, ErrInvalidQty
+	}
+	lineQty, ok := mulQty(totalQty, lineItemDetail.Qty)
+	if !ok {
+		return nil, ErrQtyOverflow
+	}
+	if d.MaxQty > 0 && lineQty > d.MaxQty {
+		return nil, fmt.Errorf("%w: %d of %d", ErrQtyExceeded, lineQty, d.MaxQty)
+	}
+	if lineQty == 0 || len(productParts) == 0 {
+		// a cancelled or empty line, there is nothing to put a price on
+		if lineTotal != 0 {
+			return nil, ErrInvalidQty
+		}
+		return &DiffuseResult{Diagnostics: diagnostics}, nil
+	}
+
+	free := freeParts(productParts, lineItemDetail.FreeProducts)
+	paidQty := lineQty
+	if len(free) > 0 {
+		paidQty = 0
+		for i, productPart := range productParts {
+			if free[i] {
+				continue
+			}
+			qty, ok := mulQty(productPart.Qty, lineItemDetail.Qty)
+			if !ok {
+				return nil, ErrQtyOverflow
+			}
+			if paidQty, ok = addQty(paidQty, qty); !ok {
+				return nil, ErrQtyOverflow
+			}
+		}
+		if paidQty == 0 {
+			if lineTotal != 0 {
+				return nil, ErrInvalidQty
+			}
+			paidQty = lineQty
+		}
 	}
 
 	var cleanedOrders []CleanedOrder
-	unitPrice := lineItemDetail.TotalPrice / float64(totalQty*lineItemDetail.Qty)
+	var unitPrice float64
+	if lineTotal != 0 {
+		unitPrice = lineTotal / float64(paidQty)
+	}
 
-	for _, productPart := range productParts {
-		qty := productPart.Qty * lineItemDetail.Qty
+	for i, productPart := range productParts {
+		qty, ok := mulQty(productPart.Qty, lineItemDetail.Qty)
+		if !ok {
+			return nil, ErrQtyOverflow
+		}
+
+		partUnitPrice := unitPrice
+		if free[i] {
+			partUnitPrice = 0
+			logAttrs(ctx, logger, slog.LevelInfo, "free product", slog.String("product_id", productPart.ProductId()), slog.Int("qty", qty))
+		}
 
 		cleanedOrders = append(cleanedOrders, CleanedOrder{
-			ProductId:  productPart.ProductId(),
-			MaterialId: productPart.MaterialId(),
-			ModelId:    productPart.ModelId,
-			TextureId:  productPart.TextureId,
-			Qty:        qty,
-			UnitPrice:  unitPrice,
-			TotalPrice: unitPrice * float64(qty),
+			ProductId:     productPart.ProductId(),
+			MaterialId:    productPart.MaterialId(),
+			ModelId:       productPart.ModelId,
+			TextureId:     productPart.TextureId,
+			Brand:         productPart.Brand,
+			Series:        productPart.Series,
+			FilmMaterial:  productPart.FilmMaterial,
+			FilmThickness: productPart.FilmThickness,
+			Qty:           qty,
+			UnitPrice:     partUnitPrice,
+			TotalPrice:    partUnitPrice * float64(qty),
 		})
 
 	}
 
-	return cleanedOrders, nil
+	if len(d.Floors) > 0 && lineTotal > 0 {
+		diagnostics = append(diagnostics, d.applyFloors(ctx, logger, cleanedOrders, free, lineTotal)...)
+	}
+
+	if d.Explain {
+		for i := range cleanedOrders {
+			trace := &PriceTrace{
+				LineQty:        lineItemDetail.Qty,
+				LineUnitPrice:  lineItemDetail.UnitPrice,
+				LineTotalPrice: lineItemDetail.TotalPrice,
+				AllocatedTotal: lineTotal,
+				TotalQty:       totalQty,
+				PaidQty:        paidQty,
+				Free:           free[i],
+			}
+			if lineTotal != 0 {
+				trace.Weight = roundTrace(cleanedOrders[i].TotalPrice / lineTotal)
+			}
+			if !free[i] {
+				trace.Floor = d.floor(cleanedOrders[i])
+			}
+			cleanedOrders[i].Trace = trace
+		}
+	}
+
+	return &DiffuseResult{
+		Orders:      cleanedOrders,
+		Diagnostics: diagnostics,
+	}, nil
+}
+
+// freeParts returns the indexes of the free members of a bundle, or nil when
+// none or all of them are free.
+func freeParts(productParts []ProductParts, freeProducts []string) map[int]bool {
+	if len(freeProducts) == 0 {
+		return nil
+	}
+
+	freeIds := make(map[string]bool, len(freeProducts))
+	for _, id := range freeProducts {
+		freeIds[id] = true
+	}
+
+	free := make(map[int]bool)
+	for i, productPart := range productParts {
+		if freeIds[productPart.ProductId()] || freeIds[productPart.MaterialId()] {
+			free[i] = true
+		}
+	}
+	if len(free) == 0 || len(free) == len(productParts) {
+		return nil
+	}
+	return free
+}
+
+// floor returns the minimum unit price of order.
+func (d *Diffuser) floor(order CleanedOrder) float64 {
+	if floor, ok := d.Floors[order.ProductId]; ok {
+		return floor
+	}
+	return d.Floors[order.MaterialId]
+}
+
+// applyFloors reprices the paid orders of a line so none is below its floor,
+// keeping the line total. Orders priced at the equal share are raised to their
+// floor one after another, and the rest of the line total is shared again by
+// the orders without a floor above it.
+func (d *Diffuser) applyFloors(ctx context.Context, logger *slog.Logger, orders []CleanedOrder, free map[int]bool, lineTotal float64) []Diagnostic {
+	floors := make([]float64, len(orders))
+	var floorTotal float64
+	for i := range orders {
+		if free[i] {
+			continue
+		}
+		floors[i] = d.floor(orders[i])
+		floorTotal += floors[i] * float64(orders[i].Qty)
+	}
+
+	atFloor := map[int]bool{}
+	for {
+		remaining := lineTotal
+		var sharedQty int
+		for i := range orders {
+			if free[i] {
+				continue
+			}
+			if atFloor[i] {
+				remaining -= floors[i] * float64(orders[i].Qty)
+			} else {
+				sharedQty += orders[i].Qty
+			}
+		}
+
+		if remaining < 0 || (sharedQty == 0 && remaining > 1e-9) {
+			break
+		}
+
+		var share float64
+		if sharedQty > 0 {
+			share = remaining / float64(sharedQty)
+		}
+		raised := false
+		for i := range orders {
+			if !free[i] && !atFloor[i] && floors[i] > share {
+				atFloor[i] = true
+				raised = true
+			}
+		}
+		if raised {
+			continue
+		}
+
+		for i := range orders {
+			if free[i] {
+				continue
+			}
+			unitPrice := share
+			if atFloor[i] {
+				unitPrice = floors[i]
+				logAttrs(ctx, logger, slog.LevelInfo, "unit price raised to floor",
+					slog.String("product_id", orders[i].ProductId),
+					slog.Float64("unit_price", orders[i].UnitPrice),
+					slog.Float64("floor", floors[i]),
+				)
+			}
+			orders[i].UnitPrice = unitPrice
+			orders[i].TotalPrice = unitPrice * float64(orders[i].Qty)
+		}
+		return nil
+	}
+
+	// the floors do not add up to the line total, price by floor
+	var diagnostics []Diagnostic
+	for i := range orders {
+		if free[i] {
+			continue
+		}
+		unitPrice := floors[i] * lineTotal / floorTotal
+		orders[i].UnitPrice = unitPrice
+		orders[i].TotalPrice = unitPrice * float64(orders[i].Qty)
+		if unitPrice < floors[i] {
+			diagnostic := Diagnostic{
+				Code:    DiagnosticPriceBelowFloor,
+				Message: "unit price " + formatAmount(unitPrice) + " of " + orders[i].ProductId + " is below floor " + formatAmount(floors[i]),
+				Value:   orders[i].ProductId,
+			}
+			diagnostics = append(diagnostics, diagnostic)
+			logAttrs(ctx, logger, slog.LevelWarn, diagnostic.Message, slog.String("code", string(diagnostic.Code)), slog.String("value", diagnostic.Value))
+		}
+	}
+	return diagnostics
+}
+
+// lineTotal validates UnitPrice * Qty against TotalPrice and returns the
+// trusted line total.
+func (d *Diffuser) lineTotal(lineItemDetail LineItemDetail) (float64, []Diagnostic, error) {
+	expected := lineItemDetail.UnitPrice * float64(lineItemDetail.Qty)
+	tolerance := max(d.AbsTolerance, d.RelTolerance*math.Abs(lineItemDetail.TotalPrice), d.UnitTolerance*float64(lineItemDetail.Qty))
+	// absorbs the float error of UnitPrice * Qty, e.g. 33.33 * 3
+	tolerance += 1e-9
+
+	if expected-lineItemDetail.TotalPrice > tolerance {
+		return 0, nil, ErrInvalidUnitPrice
+	}
+
+	var diagnostics []Diagnostic
+	if lineItemDetail.TotalPrice-expected > tolerance {
+		diagnostics = append(diagnostics, Diagnostic{
+			Code:    DiagnosticPriceSurcharge,
+			Message: "total price " + formatAmount(lineItemDetail.TotalPrice) + " exceeds unit price " + formatAmount(lineItemDetail.UnitPrice) + " * qty " + strconv.Itoa(lineItemDetail.Qty),
+			Value:   formatAmount(lineItemDetail.TotalPrice - expected),
+		})
+	}
+
+	if d.Trust == TrustUnitPrice {
+		return expected, diagnostics, nil
+	}
+	return lineItemDetail.TotalPrice, diagnostics, nil
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(roundMoney(amount), 'f', -1, 64)
 }
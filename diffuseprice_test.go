@@ -1,6 +1,7 @@
 package productmapper_test
 
 import (
+	"math"
 	"testing"
 
 	"github.com/Kritsana135/productmapper"
@@ -295,6 +296,24 @@ func TestDiffusePrice(t *testing.T) {
 			},
 			expectedError: productmapper.ErrInvalidUnitPrice,
 		},
+		{
+			name: "total quantity times line quantity overflows",
+			productParts: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "IPHONE16PROMAX",
+					Qty:        math.MaxInt / 2,
+				},
+			},
+			totalQty: math.MaxInt / 2,
+			lineItemDetail: productmapper.LineItemDetail{
+				Qty:        3,
+				UnitPrice:  1,
+				TotalPrice: 100,
+			},
+			expectedError: productmapper.ErrQtyOverflow,
+		},
 	}
 
 	for _, test := range tests {
@@ -307,3 +326,358 @@ func TestDiffusePrice(t *testing.T) {
 		})
 	}
 }
+
+func TestDiffuser(t *testing.T) {
+	productParts := []productmapper.ProductParts{
+		{
+			FilmTypeId: "FG0A",
+			TextureId:  "CLEAR",
+			ModelId:    "OPPOA3",
+			Qty:        1,
+		},
+	}
+
+	tests := []struct {
+		name                string
+		diffuser            productmapper.Diffuser
+		lineItemDetail      productmapper.LineItemDetail
+		expectedTotalPrice  float64
+		expectedDiagnostics []productmapper.Diagnostic
+		expectedError       error
+	}{
+		{
+			name:               "rounded unit price within absolute tolerance",
+			diffuser:           productmapper.Diffuser{AbsTolerance: 0.05},
+			lineItemDetail:     productmapper.LineItemDetail{Qty: 3, UnitPrice: 33.34, TotalPrice: 100},
+			expectedTotalPrice: 100,
+		},
+		{
+			name:           "rounded unit price without tolerance",
+			lineItemDetail: productmapper.LineItemDetail{Qty: 3, UnitPrice: 33.34, TotalPrice: 100},
+			expectedError:  productmapper.ErrInvalidUnitPrice,
+		},
+		{
+			name:               "rounded unit price within the default unit tolerance",
+			diffuser:           *productmapper.NewDiffuser(),
+			lineItemDetail:     productmapper.LineItemDetail{Qty: 3, UnitPrice: 33.34, TotalPrice: 100},
+			expectedTotalPrice: 100,
+		},
+		{
+			name:           "unit price beyond the default unit tolerance",
+			diffuser:       *productmapper.NewDiffuser(),
+			lineItemDetail: productmapper.LineItemDetail{Qty: 3, UnitPrice: 33.35, TotalPrice: 100},
+			expectedError:  productmapper.ErrInvalidUnitPrice,
+		},
+		{
+			name:               "unit price within relative tolerance",
+			diffuser:           productmapper.Diffuser{RelTolerance: 0.01},
+			lineItemDetail:     productmapper.LineItemDetail{Qty: 2, UnitPrice: 50.5, TotalPrice: 100},
+			expectedTotalPrice: 100,
+		},
+		{
+			name:           "unit price beyond relative tolerance",
+			diffuser:       productmapper.Diffuser{RelTolerance: 0.01},
+			lineItemDetail: productmapper.LineItemDetail{Qty: 2, UnitPrice: 51, TotalPrice: 100},
+			expectedError:  productmapper.ErrInvalidUnitPrice,
+		},
+		{
+			name:               "trust unit price",
+			diffuser:           productmapper.Diffuser{AbsTolerance: 0.05, Trust: productmapper.TrustUnitPrice},
+			lineItemDetail:     productmapper.LineItemDetail{Qty: 3, UnitPrice: 33.34, TotalPrice: 100},
+			expectedTotalPrice: 100.02,
+		},
+		{
+			name:               "surcharge is a warning",
+			lineItemDetail:     productmapper.LineItemDetail{Qty: 2, UnitPrice: 50, TotalPrice: 110},
+			expectedTotalPrice: 110,
+			expectedDiagnostics: []productmapper.Diagnostic{
+				{
+					Code:    productmapper.DiagnosticPriceSurcharge,
+					Message: "total price 110 exceeds unit price 50 * qty 2",
+					Value:   "10",
+				},
+			},
+		},
+		{
+			name:               "surcharge within tolerance",
+			diffuser:           productmapper.Diffuser{AbsTolerance: 0.05},
+			lineItemDetail:     productmapper.LineItemDetail{Qty: 3, UnitPrice: 33.33, TotalPrice: 100},
+			expectedTotalPrice: 100,
+		},
+		{
+			name:               "surcharge dropped when trusting unit price",
+			diffuser:           productmapper.Diffuser{Trust: productmapper.TrustUnitPrice},
+			lineItemDetail:     productmapper.LineItemDetail{Qty: 2, UnitPrice: 50, TotalPrice: 110},
+			expectedTotalPrice: 100,
+			expectedDiagnostics: []productmapper.Diagnostic{
+				{
+					Code:    productmapper.DiagnosticPriceSurcharge,
+					Message: "total price 110 exceeds unit price 50 * qty 2",
+					Value:   "10",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.diffuser.Diffuse(productParts, 1, test.lineItemDetail)
+
+			assert.Equal(t, test.expectedError, err)
+			if test.expectedError != nil {
+				return
+			}
+
+			assert.InDelta(t, test.expectedTotalPrice, result.Orders[0].TotalPrice, 1e-9)
+			assert.Equal(t, test.expectedDiagnostics, result.Diagnostics)
+		})
+	}
+}
+
+func TestDiffuserMaxQty(t *testing.T) {
+	productParts := []productmapper.ProductParts{
+		{
+			FilmTypeId: "FG0A",
+			TextureId:  "CLEAR",
+			ModelId:    "OPPOA3",
+			Qty:        1000,
+		},
+	}
+	lineItemDetail := productmapper.LineItemDetail{Qty: 1000000, UnitPrice: 1, TotalPrice: 1000000}
+
+	_, err := productmapper.DiffusePrice(productParts, 1000, lineItemDetail)
+	assert.ErrorIs(t, err, productmapper.ErrQtyExceeded)
+
+	_, err = (&productmapper.Diffuser{MaxQty: 5}).Diffuse(productParts[:1], 1, productmapper.LineItemDetail{Qty: 6, UnitPrice: 1, TotalPrice: 6})
+	assert.ErrorIs(t, err, productmapper.ErrQtyExceeded)
+
+	result, err := (&productmapper.Diffuser{}).Diffuse(productParts, 1000, lineItemDetail)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000000000, result.Orders[0].Qty)
+}
+
+func TestDiffuserFreeLines(t *testing.T) {
+	bundle := []productmapper.ProductParts{
+		{
+			FilmTypeId: "FG0A",
+			TextureId:  "CLEAR",
+			ModelId:    "OPPOA3",
+			Qty:        2,
+		},
+		{
+			FilmTypeId: "FG0A",
+			TextureId:  "MATTE",
+			ModelId:    "OPPOA3",
+			Qty:        1,
+		},
+	}
+
+	type price struct {
+		qty        int
+		unitPrice  float64
+		totalPrice float64
+	}
+
+	tests := []struct {
+		name           string
+		productParts   []productmapper.ProductParts
+		totalQty       int
+		lineItemDetail productmapper.LineItemDetail
+		expected       []price
+		expectedError  error
+	}{
+		{
+			name:           "platform sponsored gift",
+			productParts:   bundle,
+			totalQty:       3,
+			lineItemDetail: productmapper.LineItemDetail{Qty: 1},
+			expected:       []price{{qty: 2}, {qty: 1}},
+		},
+		{
+			name:           "free bundle member by material id",
+			productParts:   bundle,
+			totalQty:       3,
+			lineItemDetail: productmapper.LineItemDetail{Qty: 2, UnitPrice: 50, TotalPrice: 100, FreeProducts: []string{"FG0A-MATTE"}},
+			expected:       []price{{qty: 4, unitPrice: 25, totalPrice: 100}, {qty: 2}},
+		},
+		{
+			name:           "free bundle member by product id",
+			productParts:   bundle,
+			totalQty:       3,
+			lineItemDetail: productmapper.LineItemDetail{Qty: 1, UnitPrice: 90, TotalPrice: 90, FreeProducts: []string{"FG0A-CLEAR-OPPOA3"}},
+			expected:       []price{{qty: 2}, {qty: 1, unitPrice: 90, totalPrice: 90}},
+		},
+		{
+			name:           "every member free keeps the price",
+			productParts:   bundle,
+			totalQty:       3,
+			lineItemDetail: productmapper.LineItemDetail{Qty: 1, UnitPrice: 90, TotalPrice: 90, FreeProducts: []string{"FG0A-CLEAR", "FG0A-MATTE"}},
+			expected:       []price{{qty: 2, unitPrice: 30, totalPrice: 60}, {qty: 1, unitPrice: 30, totalPrice: 30}},
+		},
+		{
+			name:           "cancelled line",
+			productParts:   bundle,
+			totalQty:       3,
+			lineItemDetail: productmapper.LineItemDetail{Qty: 0},
+		},
+		{
+			name:           "zero quantity with a price",
+			productParts:   bundle,
+			totalQty:       3,
+			lineItemDetail: productmapper.LineItemDetail{Qty: 0, TotalPrice: 90},
+			expectedError:  productmapper.ErrInvalidQty,
+		},
+		{
+			name:           "no products with a price",
+			lineItemDetail: productmapper.LineItemDetail{Qty: 1, UnitPrice: 90, TotalPrice: 90},
+			expectedError:  productmapper.ErrInvalidQty,
+		},
+		{
+			name:           "negative quantity",
+			productParts:   bundle,
+			totalQty:       3,
+			lineItemDetail: productmapper.LineItemDetail{Qty: -1},
+			expectedError:  productmapper.ErrInvalidQty,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := productmapper.NewDiffuser().Diffuse(test.productParts, test.totalQty, test.lineItemDetail)
+
+			assert.Equal(t, test.expectedError, err)
+			if test.expectedError != nil {
+				return
+			}
+
+			var actual []price
+			for _, order := range result.Orders {
+				assert.False(t, math.IsNaN(order.UnitPrice))
+				actual = append(actual, price{qty: order.Qty, unitPrice: order.UnitPrice, totalPrice: order.TotalPrice})
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestDiffuserFloors(t *testing.T) {
+	bundle := []productmapper.ProductParts{
+		{
+			FilmTypeId: "FG0A",
+			TextureId:  "CLEAR",
+			ModelId:    "OPPOA3",
+			Qty:        1,
+		},
+		{
+			FilmTypeId: "FG0A",
+			TextureId:  "PRIVACY",
+			ModelId:    "OPPOA3",
+			Qty:        1,
+		},
+		{
+			FilmTypeId: "FG0A",
+			TextureId:  "MATTE",
+			ModelId:    "OPPOA3",
+			Qty:        2,
+		},
+	}
+
+	tests := []struct {
+		name                string
+		diffuser            productmapper.Diffuser
+		freeProducts        []string
+		totalPrice          float64
+		expectedUnitPrices  []float64
+		expectedDiagnostics []productmapper.Diagnostic
+	}{
+		{
+			name:               "floors below the equal share",
+			diffuser:           productmapper.Diffuser{Floors: map[string]float64{"FG0A-PRIVACY": 20}},
+			totalPrice:         100,
+			expectedUnitPrices: []float64{25, 25, 25},
+		},
+		{
+			name:               "shortfall taken from the other products",
+			diffuser:           productmapper.Diffuser{Floors: map[string]float64{"FG0A-PRIVACY": 40}},
+			totalPrice:         100,
+			expectedUnitPrices: []float64{20, 40, 20},
+		},
+		{
+			name: "product id floor takes precedence",
+			diffuser: productmapper.Diffuser{Floors: map[string]float64{
+				"FG0A-PRIVACY":        70,
+				"FG0A-PRIVACY-OPPOA3": 40,
+				"FG0A-CLEAR":          30,
+			}},
+			totalPrice:         100,
+			expectedUnitPrices: []float64{30, 40, 15},
+		},
+		{
+			name: "floors raised one after another",
+			diffuser: productmapper.Diffuser{Floors: map[string]float64{
+				"FG0A-PRIVACY": 40,
+				"FG0A-CLEAR":   25,
+			}},
+			totalPrice:         100,
+			expectedUnitPrices: []float64{25, 40, 17.5},
+		},
+		{
+			name: "floors not met",
+			diffuser: productmapper.Diffuser{Floors: map[string]float64{
+				"FG0A-PRIVACY": 60,
+				"FG0A-CLEAR":   40,
+				"FG0A-MATTE":   25,
+			}},
+			totalPrice:         75,
+			expectedUnitPrices: []float64{20, 30, 12.5},
+			expectedDiagnostics: []productmapper.Diagnostic{
+				{
+					Code:    productmapper.DiagnosticPriceBelowFloor,
+					Message: "unit price 20 of FG0A-CLEAR-OPPOA3 is below floor 40",
+					Value:   "FG0A-CLEAR-OPPOA3",
+				},
+				{
+					Code:    productmapper.DiagnosticPriceBelowFloor,
+					Message: "unit price 30 of FG0A-PRIVACY-OPPOA3 is below floor 60",
+					Value:   "FG0A-PRIVACY-OPPOA3",
+				},
+				{
+					Code:    productmapper.DiagnosticPriceBelowFloor,
+					Message: "unit price 12.5 of FG0A-MATTE-OPPOA3 is below floor 25",
+					Value:   "FG0A-MATTE-OPPOA3",
+				},
+			},
+		},
+		{
+			name: "free products have no floor",
+			diffuser: productmapper.Diffuser{
+				Floors: map[string]float64{"FG0A-PRIVACY": 70, "FG0A-MATTE": 50},
+			},
+			freeProducts:       []string{"FG0A-MATTE"},
+			totalPrice:         100,
+			expectedUnitPrices: []float64{30, 70, 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := test.diffuser.Diffuse(bundle, 4, productmapper.LineItemDetail{
+				Qty:          1,
+				UnitPrice:    test.totalPrice,
+				TotalPrice:   test.totalPrice,
+				FreeProducts: test.freeProducts,
+			})
+			assert.NoError(t, err)
+
+			var unitPrices []float64
+			var total float64
+			for _, order := range result.Orders {
+				unitPrices = append(unitPrices, order.UnitPrice)
+				total += order.TotalPrice
+			}
+			assert.InDeltaSlice(t, test.expectedUnitPrices, unitPrices, 1e-9)
+			assert.InDelta(t, test.totalPrice, total, 1e-9)
+			assert.Equal(t, test.expectedDiagnostics, result.Diagnostics)
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package productmapper
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	DiscountShopVoucher     = "SHOP_VOUCHER"
+	DiscountPlatformVoucher = "PLATFORM_VOUCHER"
+	DiscountPlatformCoins   = "PLATFORM_COINS"
+)
+
+type OrderDiscount struct {
+	Type   string // SHOP_VOUCHER, PLATFORM_VOUCHER, PLATFORM_COINS
+	Amount float64
+}
+
+// OrderHeader holds the order level amounts that marketplaces do not split
+// onto the InputOrder lines of an order.
+type OrderHeader struct {
+	OrderId   string
+	Discounts []OrderDiscount
+	Fees      []OrderFee
+}
+
+type ProrationBasis int
+
+const (
+	// ProrateByValue spreads amounts by line TotalPrice.
+	ProrateByValue ProrationBasis = iota
+	// ProrateByQty spreads amounts by line Qty.
+	ProrateByQty
+	// ProrateByWeight spreads amounts by a WeightFunc.
+	ProrateByWeight
+)
+
+// WeightFunc returns the share of an order level amount a line takes relative to the other lines.
+type WeightFunc func(order CleanedOrder) float64
+
+var (
+	ErrDiscountExceedsTotal = errors.New("discount exceeds total price")
+	ErrInvalidWeight        = errors.New("invalid proration weight")
+)
+
+// prorationWeights returns the indexes of the sellable lines that take a
+// share of order level amounts and their weights. Lines without a price only
+// take a share when weighted by a WeightFunc.
+func prorationWeights(orders []CleanedOrder, basis ProrationBasis, weight WeightFunc) ([]int, []float64, error) {
+	if basis == ProrateByWeight && weight == nil {
+		return nil, nil, fmt.Errorf("%w: missing weight func", ErrInvalidWeight)
+	}
+
+	var eligible []int
+	var weights []float64
+	for i, order := range orders {
+		if !order.IsSellable() || (basis != ProrateByWeight && order.TotalPrice <= 0) {
+			continue
+		}
+
+		var w float64
+		switch basis {
+		case ProrateByQty:
+			w = float64(order.Qty)
+		case ProrateByWeight:
+			w = weight(order)
+			if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+				return nil, nil, fmt.Errorf("%w: %v for %s", ErrInvalidWeight, w, order.ProductId)
+			}
+			if w == 0 {
+				continue
+			}
+		default:
+			w = order.TotalPrice
+		}
+
+		eligible = append(eligible, i)
+		weights = append(weights, w)
+	}
+
+	return eligible, weights, nil
+}
+
+// ProrateDiscounts spreads every discount of header over the sellable lines
+// with a price, adding it to their Discount. Each discount is allocated on
+// its own and rounded to 2 decimals so the line discounts add up to the
+// header amounts. A line takes at most its TotalPrice, the rest of its share
+// goes to the other lines. Mapper.CleanWithHeader runs it in the prorate stage, between
+// the diffuse and complementary stages.
+func ProrateDiscounts(orders []CleanedOrder, header OrderHeader, basis ProrationBasis) ([]CleanedOrder, error) {
+	prorated := make([]CleanedOrder, len(orders))
+	copy(prorated, orders)
+
+	if basis == ProrateByWeight {
+		return nil, fmt.Errorf("%w: discounts are prorated by value or by quantity", ErrInvalidWeight)
+	}
+	eligible, weights, err := prorationWeights(prorated, basis, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var total float64
+	for _, i := range eligible {
+		total += prorated[i].TotalPrice
+	}
+
+	var discountTotal float64
+	for _, discount := range header.Discounts {
+		if discount.Amount == 0 {
+			continue
+		}
+		if discount.Amount < 0 {
+			return nil, fmt.Errorf("invalid %s discount %v", discount.Type, discount.Amount)
+		}
+		discountTotal += discount.Amount
+		if len(eligible) == 0 || roundMoney(discountTotal) > roundMoney(total) {
+			return nil, fmt.Errorf("%w: %v discount on %v", ErrDiscountExceedsTotal, discountTotal, total)
+		}
+
+		caps := make([]float64, len(eligible))
+		for j, i := range eligible {
+			caps[j] = prorated[i].TotalPrice - prorated[i].Discount
+		}
+		amounts, capped, ok := allocateCapped(discount.Amount, weights, caps)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v discount on %v", ErrDiscountExceedsTotal, discountTotal, total)
+		}
+
+		for j, amount := range amounts {
+			line := &prorated[eligible[j]]
+			line.Discount += amount
+			if capped[j] {
+				line.Discount = line.TotalPrice
+			} else if roundMoney(amount) == amount {
+				line.Discount = roundMoney(line.Discount)
+			}
+			if line.Trace != nil {
+				line.Trace = line.Trace.withAdjustment(traceAdjustment(AdjustmentDiscount, discount.Type, discount.Amount, weights, j, amount))
+			}
+		}
+	}
+
+	for _, i := range eligible {
+		// the diffused prices are not rounded, a rounded discount may be above by half a satang
+		if prorated[i].Discount > prorated[i].TotalPrice+0.005 {
+			return nil, fmt.Errorf("%w: %v discount on %s priced %v", ErrDiscountExceedsTotal, prorated[i].Discount, prorated[i].ProductId, prorated[i].TotalPrice)
+		}
+	}
+
+	return prorated, nil
+}
+
+// allocateCapped is allocate with every share at most its cap, the excess of
+// a capped share goes to the other shares by weight. capped marks the shares
+// that are at their cap, ok is false when the caps cannot hold total.
+func allocateCapped(total float64, weights []float64, caps []float64) (shares []float64, capped []bool, ok bool) {
+	shares = make([]float64, len(weights))
+	capped = make([]bool, len(weights))
+
+	open := make([]int, len(weights))
+	for i := range open {
+		open[i] = i
+	}
+	remaining := total
+	for len(open) > 0 {
+		openWeights := make([]float64, len(open))
+		for j, i := range open {
+			openWeights[j] = weights[i]
+		}
+		allocated := allocate(remaining, openWeights)
+
+		var next []int
+		for j, i := range open {
+			if allocated[j] > caps[i] {
+				shares[i] = caps[i]
+				capped[i] = true
+				remaining -= caps[i]
+			} else {
+				next = append(next, i)
+			}
+		}
+		if len(next) == len(open) {
+			for j, i := range open {
+				shares[i] = allocated[j]
+			}
+			return shares, capped, true
+		}
+		open = next
+	}
+
+	return shares, capped, toSatang(remaining) == 0
+}
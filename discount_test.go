@@ -0,0 +1,176 @@
+package productmapper_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProrateDiscounts(t *testing.T) {
+	orders := []productmapper.CleanedOrder{
+		{
+			ProductId:  "FG0A-CLEAR-OPPOA3",
+			MaterialId: "FG0A-CLEAR",
+			ModelId:    "OPPOA3",
+			TextureId:  "CLEAR",
+			Qty:        2,
+			UnitPrice:  50,
+			TotalPrice: 100,
+		},
+		{
+			ProductId:  "FG0A-MATTE-OPPOA3",
+			MaterialId: "FG0A-MATTE",
+			ModelId:    "OPPOA3",
+			TextureId:  "MATTE",
+			Qty:        1,
+			UnitPrice:  200,
+			TotalPrice: 200,
+		},
+		{
+			ProductId: "FREE-GIFT",
+			Qty:       1,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		header    productmapper.OrderHeader
+		basis     productmapper.ProrationBasis
+		discounts []float64
+		err       error
+	}{
+		{
+			name: "by value",
+			header: productmapper.OrderHeader{
+				Discounts: []productmapper.OrderDiscount{
+					{Type: productmapper.DiscountShopVoucher, Amount: 30},
+					{Type: productmapper.DiscountPlatformCoins, Amount: 10},
+				},
+			},
+			basis:     productmapper.ProrateByValue,
+			discounts: []float64{13.33, 26.67, 0},
+		},
+		{
+			name: "by quantity",
+			header: productmapper.OrderHeader{
+				Discounts: []productmapper.OrderDiscount{
+					{Type: productmapper.DiscountShopVoucher, Amount: 30},
+				},
+			},
+			basis:     productmapper.ProrateByQty,
+			discounts: []float64{20, 10, 0},
+		},
+		{
+			name: "rounding remainder goes to the largest line",
+			header: productmapper.OrderHeader{
+				Discounts: []productmapper.OrderDiscount{
+					{Type: productmapper.DiscountPlatformVoucher, Amount: 0.01},
+				},
+			},
+			basis:     productmapper.ProrateByValue,
+			discounts: []float64{0, 0.01, 0},
+		},
+		{
+			name: "discount larger than the order",
+			header: productmapper.OrderHeader{
+				Discounts: []productmapper.OrderDiscount{
+					{Type: productmapper.DiscountShopVoucher, Amount: 250},
+					{Type: productmapper.DiscountPlatformCoins, Amount: 100},
+				},
+			},
+			err: productmapper.ErrDiscountExceedsTotal,
+		},
+		{
+			name: "discount by quantity larger than a cheap line goes to the other lines",
+			header: productmapper.OrderHeader{
+				Discounts: []productmapper.OrderDiscount{
+					{Type: productmapper.DiscountShopVoucher, Amount: 240},
+				},
+			},
+			basis:     productmapper.ProrateByQty,
+			discounts: []float64{100, 140, 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prorated, err := productmapper.ProrateDiscounts(orders, test.header, test.basis)
+
+			assert.ErrorIs(t, err, test.err)
+			if test.err != nil {
+				return
+			}
+
+			var discounts []float64
+			var total float64
+			for _, order := range prorated {
+				discounts = append(discounts, order.Discount)
+				total += order.Discount
+			}
+			assert.Equal(t, test.discounts, discounts)
+
+			var expectedTotal float64
+			for _, discount := range test.header.Discounts {
+				expectedTotal += discount.Amount
+			}
+			assert.InDelta(t, expectedTotal, total, 1e-9)
+		})
+	}
+
+	t.Run("net total price and complementary items after proration", func(t *testing.T) {
+		prorated, err := productmapper.ProrateDiscounts(orders[:2], productmapper.OrderHeader{
+			Discounts: []productmapper.OrderDiscount{
+				{Type: productmapper.DiscountShopVoucher, Amount: 30},
+			},
+		}, productmapper.ProrateByValue)
+		assert.NoError(t, err)
+
+		withComplementary := productmapper.WithComplementary(prorated, []productmapper.ComplementaryItem{
+			{
+				ProductId: "WIPING-CLOTH",
+				PerQty:    1,
+			},
+		})
+
+		assert.Equal(t, 90.0, withComplementary[0].NetTotalPrice())
+		assert.Equal(t, 180.0, withComplementary[1].NetTotalPrice())
+		assert.Equal(t, productmapper.CleanedOrder{No: 3, ProductId: "WIPING-CLOTH", Qty: 3}, withComplementary[2])
+		assert.Zero(t, orders[0].Discount, "input orders are not modified")
+	})
+}
+
+func TestProrateDiscountsCapped(t *testing.T) {
+	orders := []productmapper.CleanedOrder{
+		{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1, UnitPrice: 5, TotalPrice: 5},
+		{ProductId: "FG0A-MATTE-OPPOA3", Qty: 1, UnitPrice: 95, TotalPrice: 95},
+	}
+
+	prorated, err := productmapper.ProrateDiscounts(orders, productmapper.OrderHeader{
+		Discounts: []productmapper.OrderDiscount{
+			{Type: productmapper.DiscountShopVoucher, Amount: 20},
+		},
+	}, productmapper.ProrateByQty)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{5, 15}, []float64{prorated[0].Discount, prorated[1].Discount})
+}
+
+func TestProrateDiscountsWholeOrder(t *testing.T) {
+	result, err := productmapper.NewMapper().CleanWithHeader(context.Background(), productmapper.OrderHeader{
+		Discounts: []productmapper.OrderDiscount{
+			{Type: productmapper.DiscountShopVoucher, Amount: 100},
+		},
+	}, []productmapper.InputOrder{
+		{No: 1, PlatformProductId: "FG0A-CLEAR-A/FG0A-CLEAR-B/FG0A-CLEAR-C", Qty: 1, UnitPrice: 100, TotalPrice: 100},
+	})
+
+	assert.NoError(t, err)
+	var discount float64
+	for _, order := range result.Orders {
+		discount += order.Discount
+		assert.InDelta(t, 0, order.NetTotalPrice(), 1e-9, order.ProductId)
+	}
+	assert.InDelta(t, 100, discount, 1e-9)
+}
@@ -1,7 +1,11 @@
 package productmapper
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
@@ -12,6 +16,12 @@ type ProductParts struct {
 	TextureId  string
 	ModelId    string
 	Qty        int
+	// Brand and Series are only set when the model is found in Extractor.Models.
+	Brand  string
+	Series string
+	// FilmMaterial and FilmThickness are only set when the film type is found in Extractor.FilmTypes.
+	FilmMaterial  string
+	FilmThickness float64
 }
 
 func (p *ProductParts) ProductId() string {
@@ -23,17 +33,433 @@ func (p *ProductParts) MaterialId() string {
 }
 
 const (
-	Seperator = '-'
-	Splitter  = '/'
-	QtySymbol = '*'
+	Seperator  = '-'
+	Splitter   = '/'
+	QtySymbol  = '*'
+	GroupOpen  = '('
+	GroupClose = ')'
 )
 
+// QtyPattern is an alternative quantity syntax for a single product segment,
+// e.g. "2x FG0A-..." or "FG0A-... 2pcs".
+// Expr must have exactly one capture group holding the quantity digits and
+// must match at the start or at the end of the segment; the matched text is
+// removed before the segment is parsed.
+type QtyPattern struct {
+	Name string
+	Expr *regexp.Regexp
+}
+
+var (
+	// "2x FG0A-..." - whitespace after the x is required so junk such as
+	// "%20xFG0A-..." is not read as a quantity.
+	leadingTimesQty = regexp.MustCompile(`^\s*(\d+)\s*[xX]\s+`)
+	// "FG0A-...x2" or "FG0A-... x2" - only a lowercase x, model names such as
+	// "GALAXYX2", "VIVO X200" or "HONOR X9" would be misread otherwise.
+	trailingTimesQty = regexp.MustCompile(`\s*x(\d+)\s*$`)
+	// "(...) x 3" after a group, whitespace after the x is allowed since a
+	// group has no model name to misread.
+	groupTimesQty = regexp.MustCompile(`^x\s*(\d+)$`)
+	// "FG0A-...(2)"
+	parenthesesQty = regexp.MustCompile(`\s*\((\d+)\)\s*$`)
+	// "FG0A-... 2pcs" - whitespace before the digits is required so digits
+	// belonging to the model are never taken.
+	piecesQty = regexp.MustCompile(`(?i)\s+(\d+)\s*pcs?\.?\s*$`)
+)
+
+// DefaultQtyPatterns returns the quantity syntaxes recognized by ExtractPlatformId
+// besides the QtySymbol ('*') syntax.
+func DefaultQtyPatterns() []QtyPattern {
+	return []QtyPattern{
+		{Name: "leading-times", Expr: leadingTimesQty},
+		{Name: "trailing-times", Expr: trailingTimesQty},
+		{Name: "parentheses", Expr: parenthesesQty},
+		{Name: "pieces", Expr: piecesQty},
+	}
+}
+
+const (
+	DefaultMaxSegmentQty = 1000
+	DefaultMaxLineQty    = 1000
+)
+
+type Mode int
+
+const (
+	// ModeLenient keeps ids that fail validation and reports them as diagnostics.
+	ModeLenient Mode = iota
+	// ModeStrict rejects ids that fail validation with a ParseError.
+	ModeStrict
+)
+
+// Extractor parses platform product ids into ProductParts.
+// The zero value only understands the QtySymbol ('*') quantity syntax, has
+// no quantity limits besides int overflow and accepts any film type, texture and model.
+type Extractor struct {
+	QtyPatterns []QtyPattern
+	// Textures validates texture ids and resolves their aliases, nil accepts any texture.
+	Textures *TextureRegistry
+	// Models validates model ids and resolves their aliases, nil accepts any model.
+	Models *ModelCatalog
+	// FilmTypes anchors prefix detection on registered film type ids, nil
+	// accepts any run of uppercase letters and digits as film type id.
+	FilmTypes *FilmTypeRegistry
+	// Suggester suggests known textures and models for unknown ones, nil disables suggestions.
+	Suggester *Suggester
+	Mode      Mode
+	// MaxSegmentQty limits the quantity of a single product after group
+	// multipliers are applied, 0 means no limit.
+	MaxSegmentQty int
+	// MaxLineQty limits the total quantity of a platform product id, 0 means no limit.
+	MaxLineQty int
+	// Logger logs skipped characters at debug level and lenient mode
+	// diagnostics at warn level, nil disables logging.
+	Logger *slog.Logger
+}
+
+func NewExtractor() *Extractor {
+	return &Extractor{
+		QtyPatterns:   DefaultQtyPatterns(),
+		MaxSegmentQty: DefaultMaxSegmentQty,
+		MaxLineQty:    DefaultMaxLineQty,
+		Suggester:     NewSuggester(),
+	}
+}
+
+// ExtractPlatformId parses platformProductId with the default Extractor.
+func ExtractPlatformId(platformProductId string) ([]ProductParts, int, error) {
+	return NewExtractor().Extract(platformProductId)
+}
+
+// ParseResult is the outcome of Extractor.Parse.
+type ParseResult struct {
+	Products []ProductParts
+	TotalQty int
+	// Diagnostics are problems that did not stop the parse, e.g. an unknown
+	// texture in lenient mode.
+	Diagnostics []Diagnostic
+}
+
+// Extract parses platformProductId and drops the diagnostics, see Parse.
+func (e *Extractor) Extract(platformProductId string) ([]ProductParts, int, error) {
+	result, err := e.Parse(platformProductId)
+	if err != nil {
+		return []ProductParts{}, 0, err
+	}
+	return result.Products, result.TotalQty, nil
+}
+
 // - assume prefix contains only uppercase letters and numbers
 // - assume texture contains only uppercase letters
-func ExtractPlatformId(platformProductId string) ([]ProductParts, int, error) {
+// - every product segment is separated by Splitter and carries at most one quantity marker
+// - products wrapped in GroupOpen and GroupClose form a group, its quantity multiplies every member
+func (e *Extractor) Parse(platformProductId string) (*ParseResult, error) {
+	return e.parse(context.Background(), e.Logger, platformProductId)
+}
+
+func (e *Extractor) parse(ctx context.Context, logger *slog.Logger, platformProductId string) (*ParseResult, error) {
+	x := &extraction{
+		Extractor: e,
+		ctx:       ctx,
+		logger:    logger,
+		input:     platformProductId,
+	}
+
+	products, err := x.extractList(0, len(platformProductId))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(platformProductId) > 0 && len(products) == 0 {
+		return nil, &ParseError{
+			Message: "can't extract product from input",
+			Input:   platformProductId,
+		}
+	}
+
+	totalQty := 0
+	for _, product := range products {
+		if e.MaxSegmentQty > 0 && product.Qty > e.MaxSegmentQty {
+			return nil, &ParseError{
+				Message: "quantity of " + product.ProductId() + " exceeds maximum of " + strconv.Itoa(e.MaxSegmentQty),
+				Input:   platformProductId,
+			}
+		}
+
+		var ok bool
+		totalQty, ok = addQty(totalQty, product.Qty)
+		if !ok {
+			return nil, &ParseError{
+				Message: "quantity overflows",
+				Input:   platformProductId,
+			}
+		}
+	}
+
+	if e.MaxLineQty > 0 && totalQty > e.MaxLineQty {
+		return nil, &ParseError{
+			Message: "total quantity exceeds maximum of " + strconv.Itoa(e.MaxLineQty),
+			Input:   platformProductId,
+		}
+	}
+
+	return &ParseResult{
+		Products:    products,
+		TotalQty:    totalQty,
+		Diagnostics: x.diagnostics,
+	}, nil
+}
+
+// extraction holds the state of a single Parse call.
+type extraction struct {
+	*Extractor
+	ctx         context.Context
+	logger      *slog.Logger
+	input       string
+	diagnostics []Diagnostic
+}
+
+// invalid reports a problem with the value at index, it is an error in
+// strict mode and a diagnostic in lenient mode.
+// The closest of known, a map of id or alias -> id, are attached as suggestions.
+func (x *extraction) invalid(code DiagnosticCode, message string, value string, index int, known map[string]string) error {
+	var suggestions []string
+	if x.Suggester != nil {
+		suggestions = x.Suggester.suggestIds(value, known)
+	}
+
+	if x.Mode == ModeStrict {
+		return &ParseError{
+			Message:     message,
+			Input:       x.input,
+			Index:       index,
+			Suggestions: suggestions,
+			Code:        code,
+		}
+	}
+
+	x.diagnostics = append(x.diagnostics, Diagnostic{
+		Code:        code,
+		Message:     message,
+		Input:       x.input,
+		Index:       index,
+		Value:       value,
+		Suggestions: suggestions,
+	})
+	logAttrs(x.ctx, x.logger, slog.LevelWarn, message,
+		slog.String("code", string(code)),
+		slog.String("input", x.input),
+		slog.Int("index", index),
+		slog.Any("suggestions", suggestions),
+	)
+	return nil
+}
+
+// skipped logs the characters of x.input[start:end] that are not part of a product.
+func (x *extraction) skipped(start, end int, reason string) {
+	if x.logger == nil || strings.TrimSpace(x.input[start:end]) == "" {
+		return
+	}
+	logAttrs(x.ctx, x.logger, slog.LevelDebug, "skipped characters",
+		slog.String("skipped", x.input[start:end]),
+		slog.String("reason", reason),
+		slog.String("input", x.input),
+		slog.Int("index", start),
+	)
+}
+
+// extractList parses the Splitter separated items of x.input[start:end].
+func (x *extraction) extractList(start, end int) ([]ProductParts, error) {
 	products := []ProductParts{}
-	lenId := len(platformProductId)
 
+	depth := 0
+	itemStart := start
+	for i := start; i <= end; i++ {
+		stray := false
+		if i < end {
+			switch x.input[i] {
+			case GroupOpen:
+				depth++
+				continue
+			case GroupClose:
+				if depth == 0 {
+					// a close without an open, e.g. "FG0A-CLEAR-A)", ends the item
+					stray = true
+					break
+				}
+				depth--
+				continue
+			case Splitter:
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+
+		if depth > 0 {
+			return products, &ParseError{
+				Message: "unbalanced parentheses",
+				Input:   x.input,
+				Index:   i,
+			}
+		}
+
+		items, err := x.extractItem(itemStart, i, i < end)
+		if err != nil {
+			return products, err
+		}
+		products = append(products, items...)
+		if stray {
+			x.skipped(i, i+1, "unbalanced parentheses")
+		}
+		itemStart = i + 1
+	}
+
+	return products, nil
+}
+
+// extractItem parses x.input[start:end] which is either a single product or a group.
+func (x *extraction) extractItem(start, end int, terminated bool) ([]ProductParts, error) {
+	patternQty, hasPatternQty, start, end, err := x.matchQtyPattern(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return x.extractBounds(start, end, terminated, patternQty, hasPatternQty)
+}
+
+// extractBounds parses x.input[start:end], without its QtyPattern quantity,
+// as a single product or a group.
+func (x *extraction) extractBounds(start, end int, terminated bool, patternQty int, hasPatternQty bool) ([]ProductParts, error) {
+	open := start
+	for open < end && unicode.IsSpace(rune(x.input[open])) {
+		open++
+	}
+	if open == end || x.input[open] != GroupOpen {
+		product, ok, err := x.extractSegment(start, end, terminated, patternQty, hasPatternQty)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []ProductParts{product}, nil
+	}
+
+	return x.extractGroup(open, end, terminated, patternQty, hasPatternQty)
+}
+
+// extractGroup parses a group starting at x.input[open] with an optional
+// QtySymbol or "x" quantity after it. Parentheses without a product, e.g.
+// "(NEW) FG0A-...", are skipped and the rest of the item is parsed instead.
+func (x *extraction) extractGroup(open, end int, terminated bool, patternQty int, hasPatternQty bool) ([]ProductParts, error) {
+	depth := 0
+	closeIndex := open
+	for ; closeIndex < end; closeIndex++ {
+		if x.input[closeIndex] == GroupOpen {
+			depth++
+		} else if x.input[closeIndex] == GroupClose {
+			depth--
+			if depth == 0 {
+				break
+			}
+		}
+	}
+
+	members, err := x.extractList(open+1, closeIndex)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		x.skipped(open, closeIndex+1, "no product in parentheses")
+		return x.extractBounds(closeIndex+1, end, terminated, patternQty, hasPatternQty)
+	}
+
+	qty := 1
+	if hasPatternQty {
+		qty = patternQty
+	}
+
+	rest := strings.TrimSpace(x.input[closeIndex+1 : end])
+	if rest != "" {
+		digits := strings.TrimPrefix(rest, string(QtySymbol))
+		if match := groupTimesQty.FindStringSubmatch(rest); match != nil {
+			digits = match[1]
+		}
+		if digits == rest || digits == "" || strings.IndexFunc(digits, func(r rune) bool { return !unicode.IsDigit(r) }) >= 0 {
+			return nil, &ParseError{
+				Message: "invalid group quantity",
+				Input:   x.input,
+				Index:   closeIndex + 1,
+			}
+		}
+		if hasPatternQty {
+			return nil, &ParseError{
+				Message: "more than one quantity found in product",
+				Input:   x.input,
+			}
+		}
+		qtyVal, err := parseQty(x.input, digits)
+		if err != nil {
+			return nil, err
+		}
+		qty = qtyVal
+	}
+
+	for i := range members {
+		memberQty, ok := mulQty(members[i].Qty, qty)
+		if !ok {
+			return nil, &ParseError{
+				Message: "quantity overflows",
+				Input:   x.input,
+			}
+		}
+		members[i].Qty = memberQty
+	}
+
+	return members, nil
+}
+
+// extractSegment parses x.input[start:end]. ok is false when the segment holds no product.
+func (x *extraction) extractSegment(start, end int, terminated bool, patternQty int, hasPatternQty bool) (ProductParts, bool, error) {
+	if x.FilmTypes == nil {
+		product, filmTypeIndex, ok, err := x.parseSegment(start, end, terminated, patternQty, hasPatternQty, false)
+		x.skippedPrefix(start, end, filmTypeIndex, ok, err)
+		return product, ok, err
+	}
+
+	product, filmTypeIndex, ok, err := x.parseSegment(start, end, terminated, patternQty, hasPatternQty, true)
+	if err != nil || ok {
+		x.skippedPrefix(start, end, filmTypeIndex, ok, err)
+		return product, ok, err
+	}
+
+	// no known film type in the segment, fall back to the letters-plus-digits prefix
+	product, filmTypeIndex, ok, err = x.parseSegment(start, end, terminated, patternQty, hasPatternQty, false)
+	x.skippedPrefix(start, end, filmTypeIndex, ok, err)
+	if err != nil || !ok {
+		return product, ok, err
+	}
+	if err := x.invalid(DiagnosticUnknownFilmType, "unknown film type id '"+product.FilmTypeId+"'", product.FilmTypeId, filmTypeIndex, x.FilmTypes.names()); err != nil {
+		return ProductParts{}, false, err
+	}
+	return product, true, nil
+}
+
+// skippedPrefix logs what parseSegment skipped of x.input[start:end]: the
+// characters before the film type id, or the whole segment without a product.
+func (x *extraction) skippedPrefix(start, end int, filmTypeIndex int, ok bool, err error) {
+	switch {
+	case err != nil:
+	case ok:
+		x.skipped(start, filmTypeIndex, "before film type id")
+	default:
+		x.skipped(start, end, "no product in segment")
+	}
+}
+
+// parseSegment parses x.input[start:end] and returns the index of the film type id.
+// When anchored the film type id must be registered in FilmTypes, otherwise
+// any run of uppercase letters and digits containing both is a film type id.
+func (x *extraction) parseSegment(start, end int, terminated bool, patternQty int, hasPatternQty bool, anchored bool) (ProductParts, int, bool, error) {
 	var (
 		prefixBuilder  strings.Builder
 		textureBuilder strings.Builder
@@ -42,15 +468,20 @@ func ExtractPlatformId(platformProductId string) ([]ProductParts, int, error) {
 		prefixLetterCount int
 		prefixDigitCount  int
 
-		totalQty     = 0
+		filmTypeId    string
+		filmTypeIndex int
+		textureIndex  int
+		modelIndex    int
+
 		qty          = 1
 		hasQtySymbol = false
 		qtyDigits    []rune
+		qtyEnded     bool
 
-		state = 0 // parsing prefix, 1: parsing texture, 2: parsing model, 3: parsing quantity, 4: append products
+		state = 0 // parsing prefix, 1: parsing texture, 2: parsing model, 3: parsing quantity
 	)
 
-	for i, c := range platformProductId {
+	for i, c := range x.input[start:end] {
 		switch state {
 		case 0: // parsing prefix
 			if unicode.IsDigit(c) {
@@ -60,7 +491,14 @@ func ExtractPlatformId(platformProductId string) ([]ProductParts, int, error) {
 				prefixBuilder.WriteRune(c)
 				prefixLetterCount++
 			} else {
-				if prefixLetterCount > 0 && prefixDigitCount > 0 && c == Seperator {
+				id, ok := "", false
+				if c == Seperator {
+					id, ok = x.matchFilmType(prefixBuilder.String(), prefixLetterCount, prefixDigitCount, anchored)
+				}
+				if ok {
+					filmTypeId = id
+					filmTypeIndex = start + i - len(id)
+					textureIndex = start + i + 1
 					state = 1 // transition to parsing texture
 				} else {
 					prefixBuilder.Reset()
@@ -72,102 +510,244 @@ func ExtractPlatformId(platformProductId string) ([]ProductParts, int, error) {
 			if unicode.IsLetter(c) && unicode.IsUpper(c) {
 				textureBuilder.WriteRune(c)
 			} else if textureBuilder.Len() > 0 && c == Seperator {
+				modelIndex = start + i + 1
 				state = 2 // transition to parsing model
 			} else {
-				return products, 0, &ParseError{
+				return ProductParts{}, 0, false, &ParseError{
 					Message: "invalid texture id format",
-					Input:   platformProductId,
-					Index:   i,
+					Input:   x.input,
+					Index:   start + i,
 				}
 			}
 		case 2: // parsing model
 			if c == QtySymbol {
 				hasQtySymbol = true
 				state = 3 // transition to parsing quantity
-			} else if c == Splitter {
-				state = 4
 			} else {
 				modelBuilder.WriteRune(c)
 			}
 		case 3: // parsing quantity
-			if unicode.IsDigit(c) {
+			switch {
+			case unicode.IsDigit(c) && !qtyEnded:
 				qtyDigits = append(qtyDigits, c)
-			} else if c == Splitter {
-				state = 4
+			case unicode.IsSpace(c) && len(qtyDigits) > 0:
+				qtyEnded = true
+			default:
+				return ProductParts{}, 0, false, &ParseError{
+					Message: "invalid quantity",
+					Input:   x.input,
+					Index:   start + i,
+				}
 			}
 		}
+	}
 
-		if (i == lenId-1 && state == 2) || (i == lenId-1 && state == 3) {
-			state = 4
+	switch state {
+	case 0:
+		return ProductParts{}, 0, false, nil
+	case 1:
+		if terminated {
+			return ProductParts{}, 0, false, &ParseError{
+				Message: "invalid texture id format",
+				Input:   x.input,
+				Index:   end,
+			}
 		}
+		return ProductParts{}, 0, false, nil
+	}
 
-		if state == 4 {
-			if hasQtySymbol {
-				if len(qtyDigits) == 0 {
-					return products, 0, &ParseError{
-						Message: "quantity symbol '*' found but no digits followed",
-						Input:   platformProductId,
-					}
-				}
-				qtyVal, err := strconv.Atoi(string(qtyDigits))
-				if err != nil {
-					return products, 0, &ParseError{
-						Message: "failed to parse quantity",
-						Input:   platformProductId,
-					}
-				}
-				qty = qtyVal
+	if hasQtySymbol {
+		if len(qtyDigits) == 0 {
+			return ProductParts{}, 0, false, &ParseError{
+				Message: "quantity symbol '*' found but no digits followed",
+				Input:   x.input,
 			}
-
-			if prefixBuilder.Len() == 0 || textureBuilder.Len() == 0 || modelBuilder.Len() == 0 {
-				return products, 0, &ParseError{
-					Message: "invalid format",
-					Input:   platformProductId,
-				}
+		}
+		if hasPatternQty {
+			return ProductParts{}, 0, false, &ParseError{
+				Message: "more than one quantity found in product",
+				Input:   x.input,
 			}
+		}
+		qtyVal, err := parseQty(x.input, string(qtyDigits))
+		if err != nil {
+			return ProductParts{}, 0, false, err
+		}
+		qty = qtyVal
+	} else if hasPatternQty {
+		qty = patternQty
+	}
 
-			products = append(products, ProductParts{
-				FilmTypeId: prefixBuilder.String(),
-				TextureId:  textureBuilder.String(),
-				ModelId:    modelBuilder.String(),
-				Qty:        qty,
-			})
+	if filmTypeId == "" || textureBuilder.Len() == 0 || modelBuilder.Len() == 0 {
+		return ProductParts{}, 0, false, &ParseError{
+			Message: "invalid format",
+			Input:   x.input,
+		}
+	}
 
-			totalQty += qty
+	textureId := textureBuilder.String()
+	if x.Textures != nil {
+		canonical, ok := x.Textures.Resolve(textureId)
+		if ok {
+			textureId = canonical
+		} else if err := x.invalid(DiagnosticUnknownTexture, "unknown texture id '"+textureId+"'", textureId, textureIndex, x.Textures.textures); err != nil {
+			return ProductParts{}, 0, false, err
+		}
+	}
 
-			prefixBuilder.Reset()
-			textureBuilder.Reset()
-			modelBuilder.Reset()
+	product := ProductParts{
+		FilmTypeId: filmTypeId,
+		TextureId:  textureId,
+		ModelId:    modelBuilder.String(),
+		Qty:        qty,
+	}
 
-			prefixLetterCount = 0
-			prefixDigitCount = 0
+	if anchored {
+		filmType, _ := x.FilmTypes.Lookup(filmTypeId)
+		product.FilmMaterial = filmType.Material
+		product.FilmThickness = filmType.Thickness
+	}
 
-			qty = 1
-			hasQtySymbol = false
-			qtyDigits = []rune{}
-			state = 0
+	if x.Models != nil {
+		model, ok := x.Models.Resolve(product.ModelId)
+		if ok {
+			product.ModelId = model.ModelId
+			product.Brand = model.Brand
+			product.Series = model.Series
+		} else if err := x.invalid(DiagnosticUnknownModel, "unknown model id '"+product.ModelId+"'", product.ModelId, modelIndex, x.Models.aliases); err != nil {
+			return ProductParts{}, 0, false, err
 		}
 	}
 
-	if lenId > 0 && len(products) == 0 {
-		return products, 0, &ParseError{
-			Message: "can't extract product from input",
-			Input:   platformProductId,
+	return product, filmTypeIndex, true, nil
+}
+
+// matchFilmType returns the film type id that prefix ends with, see parseSegment.
+func (x *extraction) matchFilmType(prefix string, letters, digits int, anchored bool) (string, bool) {
+	if !anchored {
+		return prefix, letters > 0 && digits > 0
+	}
+
+	for i := range prefix {
+		if _, ok := x.FilmTypes.Lookup(prefix[i:]); ok {
+			return prefix[i:], true
 		}
 	}
+	return "", false
+}
+
+// matchQtyPattern looks for a QtyPattern in x.input[start:end] and returns the
+// segment bounds with the matched text cut off. The patterns are matched again
+// on the remaining bounds, a second quantity is an error. A trailing match that
+// is part of a model id of Models, e.g. "VIVO x200", is not a quantity.
+func (x *extraction) matchQtyPattern(start, end int) (int, bool, int, int, error) {
+	var (
+		qty      int
+		found    bool
+		newStart = start
+		newEnd   = end
+	)
+
+	for matched := true; matched; {
+		matched = false
+		segment := x.input[newStart:newEnd]
+		for _, pattern := range x.QtyPatterns {
+			loc := pattern.Expr.FindStringSubmatchIndex(segment)
+			if loc == nil || len(loc) < 4 || loc[2] < 0 {
+				continue
+			}
+			if loc[0] != 0 && loc[1] != len(segment) {
+				continue
+			}
+			if loc[0] != 0 && x.endsWithModel(segment) {
+				continue
+			}
+			if found {
+				return 0, false, start, end, &ParseError{
+					Message: "more than one quantity found in product",
+					Input:   x.input,
+				}
+			}
 
-	return products, totalQty, nil
+			qtyVal, err := parseQty(x.input, segment[loc[2]:loc[3]])
+			if err != nil {
+				return 0, false, start, end, err
+			}
+
+			qty = qtyVal
+			found = true
+			matched = true
+			if loc[0] == 0 {
+				newStart += loc[1]
+			} else {
+				newEnd = newStart + loc[0]
+			}
+			break
+		}
+	}
+
+	return qty, found, newStart, newEnd, nil
+}
+
+// endsWithModel reports whether segment ends with a model id of Models
+// following a Seperator.
+func (x *extraction) endsWithModel(segment string) bool {
+	if x.Models == nil {
+		return false
+	}
+	for i := 0; i < len(segment); i++ {
+		if segment[i] != Seperator {
+			continue
+		}
+		if _, ok := x.Models.Resolve(segment[i+1:]); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQty converts quantity digits, zero and quantities that overflow int are rejected.
+func parseQty(input string, digits string) (int, error) {
+	qty, err := strconv.Atoi(digits)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, &ParseError{
+			Message: "quantity overflows",
+			Input:   input,
+		}
+	}
+	if err != nil {
+		return 0, &ParseError{
+			Message: "failed to parse quantity",
+			Input:   input,
+		}
+	}
+	if qty <= 0 {
+		return 0, &ParseError{
+			Message: "quantity must be greater than zero",
+			Input:   input,
+		}
+	}
+	return qty, nil
 }
 
 type ParseError struct {
 	Message string
 	Input   string
 	Index   int
+	// Suggestions are the closest known ids for an unknown texture or model.
+	Suggestions []string
+	// Code is set for the ids that fail validation in strict mode.
+	Code DiagnosticCode
 }
 
 func (e *ParseError) Error() string {
+	message := "Parse Error: " + e.Message
 	if e.Index != 0 {
-		return "Parse Error: " + e.Message + " at index " + strconv.Itoa(e.Index) + " in '" + e.Input + "'"
+		message += " at index " + strconv.Itoa(e.Index)
+	}
+	message += " in '" + e.Input + "'"
+	if len(e.Suggestions) > 0 {
+		message += ", did you mean '" + strings.Join(e.Suggestions, "', '") + "'?"
 	}
-	return "Parse Error: " + e.Message + " in '" + e.Input + "'"
+	return message
 }
@@ -1,6 +1,7 @@
 package productmapper_test
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/Kritsana135/productmapper"
@@ -195,6 +196,354 @@ func TestExtractPlatformId(t *testing.T) {
 			},
 			totalQty: 0,
 		},
+		{
+			name:              "quantity written before the product",
+			platformProductId: "2x FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3",
+			expectedProducts: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "OPPOA3",
+					Qty:        2,
+				},
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "MATTE",
+					ModelId:    "OPPOA3",
+					Qty:        1,
+				},
+			},
+			totalQty: 3,
+		},
+		{
+			name:              "quantity written after the product with x",
+			platformProductId: "FG0A-CLEAR-OPPOA3x2/FG0A-MATTE-OPPOA3 x3",
+			expectedProducts: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "OPPOA3",
+					Qty:        2,
+				},
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "MATTE",
+					ModelId:    "OPPOA3",
+					Qty:        3,
+				},
+			},
+			totalQty: 5,
+		},
+		{
+			name:              "model name that ends with X and digits is not a quantity",
+			platformProductId: "FG0A-CLEAR-GALAXYX2*2",
+			expectedProducts: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "GALAXYX2",
+					Qty:        2,
+				},
+			},
+			totalQty: 2,
+		},
+		{
+			name:              "model name with a space before X and digits is not a quantity",
+			platformProductId: "FG0A-CLEAR-VIVO X200",
+			expectedProducts: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "VIVO X200",
+					Qty:        1,
+				},
+			},
+			totalQty: 1,
+		},
+		{
+			name:              "HONOR X9 is a model",
+			platformProductId: "FG0A-CLEAR-HONOR X9",
+			expectedProducts: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "HONOR X9",
+					Qty:        1,
+				},
+			},
+			totalQty: 1,
+		},
+		{
+			name:              "POCO X6 is a model",
+			platformProductId: "FG0A-CLEAR-POCO X6",
+			expectedProducts: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "POCO X6",
+					Qty:        1,
+				},
+			},
+			totalQty: 1,
+		},
+		{
+			name:              "quantity in parentheses and pieces",
+			platformProductId: "FG0A-CLEAR-OPPOA3(2)/FG0A-MATTE-OPPOA3 4pcs",
+			expectedProducts: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "OPPOA3",
+					Qty:        2,
+				},
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "MATTE",
+					ModelId:    "OPPOA3",
+					Qty:        4,
+				},
+			},
+			totalQty: 6,
+		},
+		{
+			name:              "more than one quantity in one product",
+			platformProductId: "2x FG0A-CLEAR-OPPOA3*3",
+			expectedProducts:  []productmapper.ProductParts{},
+			err: &productmapper.ParseError{
+				Message: "more than one quantity found in product",
+				Input:   "2x FG0A-CLEAR-OPPOA3*3",
+			},
+			totalQty: 0,
+		},
+		{
+			name:              "second quantity after a quantity pattern",
+			platformProductId: "FG0A-CLEAR-OPPOA3 x2 2pcs",
+			expectedProducts:  []productmapper.ProductParts{},
+			err: &productmapper.ParseError{
+				Message: "more than one quantity found in product",
+				Input:   "FG0A-CLEAR-OPPOA3 x2 2pcs",
+			},
+			totalQty: 0,
+		},
+		{
+			name:              "group multiplies every member",
+			platformProductId: "(FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3)*3",
+			expectedProducts: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "OPPOA3",
+					Qty:        3,
+				},
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "MATTE",
+					ModelId:    "OPPOA3",
+					Qty:        3,
+				},
+			},
+			totalQty: 6,
+		},
+		{
+			name:              "nested groups next to a single product",
+			platformProductId: "((FG0A-CLEAR-OPPOA3*2/FG0A-MATTE-OPPOA3) x2/FG0A-PRIVACY-OPPOA3)*3/FG0B-CLEAR-OPPOA3",
+			expectedProducts: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "OPPOA3",
+					Qty:        12,
+				},
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "MATTE",
+					ModelId:    "OPPOA3",
+					Qty:        6,
+				},
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "PRIVACY",
+					ModelId:    "OPPOA3",
+					Qty:        3,
+				},
+				{
+					FilmTypeId: "FG0B",
+					TextureId:  "CLEAR",
+					ModelId:    "OPPOA3",
+					Qty:        1,
+				},
+			},
+			totalQty: 22,
+		},
+		{
+			name:              "parentheses without a product before a bundle",
+			platformProductId: "(NEW) FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3",
+			expectedProducts: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "OPPOA3",
+					Qty:        1,
+				},
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "MATTE",
+					ModelId:    "OPPOA3",
+					Qty:        1,
+				},
+			},
+			totalQty: 2,
+		},
+		{
+			name:              "lowercase note in parentheses",
+			platformProductId: "(promo) FG0A-CLEAR-OPPOA3",
+			expectedProducts: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "OPPOA3",
+					Qty:        1,
+				},
+			},
+			totalQty: 1,
+		},
+		{
+			name:              "closing parenthesis without an opening one",
+			platformProductId: "FG0A-CLEAR-A)",
+			expectedProducts: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "A",
+					Qty:        1,
+				},
+			},
+			totalQty: 1,
+		},
+		{
+			name:              "group quantity with space after x",
+			platformProductId: "(FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3) x 3",
+			expectedProducts: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "OPPOA3",
+					Qty:        3,
+				},
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "MATTE",
+					ModelId:    "OPPOA3",
+					Qty:        3,
+				},
+			},
+			totalQty: 6,
+		},
+		{
+			name:              "group without closing parenthesis",
+			platformProductId: "(FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3*3",
+			expectedProducts:  []productmapper.ProductParts{},
+			err: &productmapper.ParseError{
+				Message: "unbalanced parentheses",
+				Input:   "(FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3*3",
+				Index:   38,
+			},
+			totalQty: 0,
+		},
+		{
+			name:              "invalid group quantity",
+			platformProductId: "(FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3)*3A",
+			expectedProducts:  []productmapper.ProductParts{},
+			err: &productmapper.ParseError{
+				Message: "invalid group quantity",
+				Input:   "(FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3)*3A",
+				Index:   37,
+			},
+			totalQty: 0,
+		},
+		{
+			name:              "second quantity symbol",
+			platformProductId: "FG0A-CLEAR-OPPOA3*2*3",
+			expectedProducts:  []productmapper.ProductParts{},
+			err: &productmapper.ParseError{
+				Message: "invalid quantity",
+				Input:   "FG0A-CLEAR-OPPOA3*2*3",
+				Index:   19,
+			},
+			totalQty: 0,
+		},
+		{
+			name:              "space inside quantity",
+			platformProductId: "FG0A-CLEAR-OPPOA3*2 3",
+			expectedProducts:  []productmapper.ProductParts{},
+			err: &productmapper.ParseError{
+				Message: "invalid quantity",
+				Input:   "FG0A-CLEAR-OPPOA3*2 3",
+				Index:   20,
+			},
+			totalQty: 0,
+		},
+		{
+			name:              "letter after quantity",
+			platformProductId: "FG0A-CLEAR-OPPOA3*2x",
+			expectedProducts:  []productmapper.ProductParts{},
+			err: &productmapper.ParseError{
+				Message: "invalid quantity",
+				Input:   "FG0A-CLEAR-OPPOA3*2x",
+				Index:   19,
+			},
+			totalQty: 0,
+		},
+		{
+			name:              "zero quantity",
+			platformProductId: "FG0A-CLEAR-OPPOA3*0",
+			expectedProducts:  []productmapper.ProductParts{},
+			err: &productmapper.ParseError{
+				Message: "quantity must be greater than zero",
+				Input:   "FG0A-CLEAR-OPPOA3*0",
+			},
+			totalQty: 0,
+		},
+		{
+			name:              "zero group quantity",
+			platformProductId: "(FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3)(0)",
+			expectedProducts:  []productmapper.ProductParts{},
+			err: &productmapper.ParseError{
+				Message: "quantity must be greater than zero",
+				Input:   "(FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3)(0)",
+			},
+			totalQty: 0,
+		},
+		{
+			name:              "quantity overflows int",
+			platformProductId: "FG0A-CLEAR-OPPOA3*99999999999999999999",
+			expectedProducts:  []productmapper.ProductParts{},
+			err: &productmapper.ParseError{
+				Message: "quantity overflows",
+				Input:   "FG0A-CLEAR-OPPOA3*99999999999999999999",
+			},
+			totalQty: 0,
+		},
+		{
+			name:              "quantity exceeds maximum per product",
+			platformProductId: "FG0A-CLEAR-OPPOA3*999999999",
+			expectedProducts:  []productmapper.ProductParts{},
+			err: &productmapper.ParseError{
+				Message: "quantity of FG0A-CLEAR-OPPOA3 exceeds maximum of 1000",
+				Input:   "FG0A-CLEAR-OPPOA3*999999999",
+			},
+			totalQty: 0,
+		},
+		{
+			name:              "group quantity exceeds maximum per product",
+			platformProductId: "(FG0A-CLEAR-OPPOA3*600/FG0A-MATTE-OPPOA3*300)*2",
+			expectedProducts:  []productmapper.ProductParts{},
+			err: &productmapper.ParseError{
+				Message: "quantity of FG0A-CLEAR-OPPOA3 exceeds maximum of 1000",
+				Input:   "(FG0A-CLEAR-OPPOA3*600/FG0A-MATTE-OPPOA3*300)*2",
+			},
+			totalQty: 0,
+		},
 	}
 
 	for _, tc := range tests {
@@ -213,3 +562,93 @@ func TestExtractPlatformId(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractorQtyPatterns(t *testing.T) {
+	t.Run("zero value only understands quantity symbol", func(t *testing.T) {
+		extractor := &productmapper.Extractor{}
+
+		products, totalQty, err := extractor.Extract("FG0A-CLEAR-OPPOA3 2pcs/FG0A-MATTE-OPPOA3*2")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []productmapper.ProductParts{
+			{
+				FilmTypeId: "FG0A",
+				TextureId:  "CLEAR",
+				ModelId:    "OPPOA3 2pcs",
+				Qty:        1,
+			},
+			{
+				FilmTypeId: "FG0A",
+				TextureId:  "MATTE",
+				ModelId:    "OPPOA3",
+				Qty:        2,
+			},
+		}, products)
+		assert.Equal(t, 3, totalQty)
+	})
+
+	t.Run("custom pattern", func(t *testing.T) {
+		extractor := &productmapper.Extractor{
+			QtyPatterns: []productmapper.QtyPattern{
+				{Name: "set", Expr: regexp.MustCompile(`\s+SET(\d+)$`)},
+			},
+		}
+
+		products, totalQty, err := extractor.Extract("FG0A-CLEAR-OPPOA3 SET5")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []productmapper.ProductParts{
+			{
+				FilmTypeId: "FG0A",
+				TextureId:  "CLEAR",
+				ModelId:    "OPPOA3",
+				Qty:        5,
+			},
+		}, products)
+		assert.Equal(t, 5, totalQty)
+	})
+}
+
+func TestExtractorQtyLimits(t *testing.T) {
+	tests := []struct {
+		name              string
+		extractor         *productmapper.Extractor
+		platformProductId string
+		totalQty          int
+		err               error
+	}{
+		{
+			name:              "no limits",
+			extractor:         &productmapper.Extractor{},
+			platformProductId: "FG0A-CLEAR-OPPOA3*5000/FG0A-MATTE-OPPOA3*5000",
+			totalQty:          10000,
+		},
+		{
+			name:              "line limit",
+			extractor:         &productmapper.Extractor{MaxLineQty: 10},
+			platformProductId: "FG0A-CLEAR-OPPOA3*6/FG0A-MATTE-OPPOA3*5",
+			err: &productmapper.ParseError{
+				Message: "total quantity exceeds maximum of 10",
+				Input:   "FG0A-CLEAR-OPPOA3*6/FG0A-MATTE-OPPOA3*5",
+			},
+		},
+		{
+			name:              "group multiplier overflows",
+			extractor:         &productmapper.Extractor{},
+			platformProductId: "((FG0A-CLEAR-OPPOA3*4294967296)*4294967296)*2",
+			err: &productmapper.ParseError{
+				Message: "quantity overflows",
+				Input:   "((FG0A-CLEAR-OPPOA3*4294967296)*4294967296)*2",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, totalQty, err := tc.extractor.Extract(tc.platformProductId)
+
+			assert.Equal(t, tc.err, err)
+			assert.Equal(t, tc.totalQty, totalQty)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package productmapper
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	FeeShipping        = "SHIPPING"
+	FeeShippingSubsidy = "SHIPPING_SUBSIDY"
+	FeeCommission      = "COMMISSION"
+	FeeTransaction     = "TRANSACTION"
+	FeeService         = "SERVICE"
+)
+
+var ErrNoLineToAllocate = errors.New("no line to allocate to")
+
+type OrderFee struct {
+	Type string // SHIPPING, SHIPPING_SUBSIDY, COMMISSION, TRANSACTION, SERVICE
+	// Amount is a cost to the shop, a negative amount is an income such as a
+	// shipping subsidy paid by the platform.
+	Amount float64
+}
+
+// AllocateFees spreads every fee of header over the sellable lines and adds
+// it to their Fees by type. Each fee is allocated on its own and rounded to 2
+// decimals so the line fees add up to the header amounts. weight is only used
+// with ProrateByWeight.
+func AllocateFees(orders []CleanedOrder, header OrderHeader, basis ProrationBasis, weight WeightFunc) ([]CleanedOrder, error) {
+	allocated := make([]CleanedOrder, len(orders))
+	copy(allocated, orders)
+
+	eligible, weights, err := prorationWeights(allocated, basis, weight)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fee := range header.Fees {
+		if fee.Amount == 0 {
+			continue
+		}
+		if len(eligible) == 0 {
+			return nil, fmt.Errorf("%w: %s fee %v", ErrNoLineToAllocate, fee.Type, fee.Amount)
+		}
+
+		for j, amount := range allocate(fee.Amount, weights) {
+			line := &allocated[eligible[j]]
+			fees := make(map[string]float64, len(line.Fees)+1)
+			for feeType, feeAmount := range line.Fees {
+				fees[feeType] = feeAmount
+			}
+			fees[fee.Type] += amount
+			if roundMoney(fee.Amount) == fee.Amount {
+				fees[fee.Type] = roundMoney(fees[fee.Type])
+			}
+			line.Fees = fees
+			if line.Trace != nil {
+				line.Trace = line.Trace.withAdjustment(traceAdjustment(AdjustmentFee, fee.Type, fee.Amount, weights, j, amount))
+			}
+		}
+	}
+
+	return allocated, nil
+}
+
+// TotalFees is the sum of the fees allocated to the line.
+func (o *CleanedOrder) TotalFees() float64 {
+	var total float64
+	for _, amount := range o.Fees {
+		total += amount
+	}
+	return total
+}
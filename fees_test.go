@@ -0,0 +1,125 @@
+package productmapper_test
+
+import (
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocateFees(t *testing.T) {
+	orders := []productmapper.CleanedOrder{
+		{
+			ProductId:     "FG0A-CLEAR-IPHONE16PROMAX",
+			Qty:           1,
+			UnitPrice:     300,
+			TotalPrice:    300,
+			FilmThickness: 0.33,
+		},
+		{
+			ProductId:     "FG0A-MATTE-OPPOA3",
+			Qty:           3,
+			UnitPrice:     33.33,
+			TotalPrice:    100,
+			FilmThickness: 0.26,
+		},
+		{
+			ProductId:  "FILM-FG0A-MATTE-OPPOA3",
+			Qty:        3,
+			TotalPrice: 100,
+			LineType:   productmapper.LineComponent,
+		},
+		{
+			ProductId: "WIPING-CLOTH",
+			Qty:       4,
+		},
+	}
+
+	header := productmapper.OrderHeader{
+		Fees: []productmapper.OrderFee{
+			{Type: productmapper.FeeShipping, Amount: 40},
+			{Type: productmapper.FeeShippingSubsidy, Amount: -20},
+			{Type: productmapper.FeeCommission, Amount: 20},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		basis    productmapper.ProrationBasis
+		weight   productmapper.WeightFunc
+		expected []map[string]float64
+		err      error
+	}{
+		{
+			name:  "by value",
+			basis: productmapper.ProrateByValue,
+			expected: []map[string]float64{
+				{productmapper.FeeShipping: 30, productmapper.FeeShippingSubsidy: -15, productmapper.FeeCommission: 15},
+				{productmapper.FeeShipping: 10, productmapper.FeeShippingSubsidy: -5, productmapper.FeeCommission: 5},
+				nil,
+				nil,
+			},
+		},
+		{
+			name:  "by quantity",
+			basis: productmapper.ProrateByQty,
+			expected: []map[string]float64{
+				{productmapper.FeeShipping: 10, productmapper.FeeShippingSubsidy: -5, productmapper.FeeCommission: 5},
+				{productmapper.FeeShipping: 30, productmapper.FeeShippingSubsidy: -15, productmapper.FeeCommission: 15},
+				nil,
+				nil,
+			},
+		},
+		{
+			name:  "by custom weight including lines without a price",
+			basis: productmapper.ProrateByWeight,
+			weight: func(order productmapper.CleanedOrder) float64 {
+				if order.ProductId == "WIPING-CLOTH" {
+					return 1
+				}
+				return float64(order.Qty) * order.FilmThickness
+			},
+			expected: []map[string]float64{
+				{productmapper.FeeShipping: 6.26, productmapper.FeeShippingSubsidy: -3.13, productmapper.FeeCommission: 3.13},
+				{productmapper.FeeShipping: 14.79, productmapper.FeeShippingSubsidy: -7.39, productmapper.FeeCommission: 7.39},
+				nil,
+				{productmapper.FeeShipping: 18.95, productmapper.FeeShippingSubsidy: -9.48, productmapper.FeeCommission: 9.48},
+			},
+		},
+		{
+			name:  "custom weight without weight func",
+			basis: productmapper.ProrateByWeight,
+			err:   productmapper.ErrInvalidWeight,
+		},
+		{
+			name:   "negative custom weight",
+			basis:  productmapper.ProrateByWeight,
+			weight: func(order productmapper.CleanedOrder) float64 { return -1 },
+			err:    productmapper.ErrInvalidWeight,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			allocated, err := productmapper.AllocateFees(orders, header, test.basis, test.weight)
+
+			assert.ErrorIs(t, err, test.err)
+			if test.err != nil {
+				return
+			}
+
+			var fees []map[string]float64
+			for _, order := range allocated {
+				fees = append(fees, order.Fees)
+			}
+			assert.Equal(t, test.expected, fees)
+			assert.InDelta(t, 40.0, allocated[0].TotalFees()+allocated[1].TotalFees()+allocated[3].TotalFees(), 1e-9)
+		})
+	}
+
+	t.Run("no line to allocate to", func(t *testing.T) {
+		_, err := productmapper.AllocateFees(orders[3:], header, productmapper.ProrateByValue, nil)
+
+		assert.ErrorIs(t, err, productmapper.ErrNoLineToAllocate)
+	})
+}
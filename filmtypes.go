@@ -0,0 +1,59 @@
+package productmapper
+
+import (
+	"slices"
+	"strings"
+)
+
+type FilmType struct {
+	FilmTypeId string
+	Material   string
+	// Thickness in millimetres.
+	Thickness float64
+}
+
+// FilmTypeRegistry holds the known film types.
+type FilmTypeRegistry struct {
+	filmTypes map[string]FilmType
+}
+
+func NewFilmTypeRegistry(filmTypes ...FilmType) *FilmTypeRegistry {
+	r := &FilmTypeRegistry{
+		filmTypes: map[string]FilmType{},
+	}
+	for _, filmType := range filmTypes {
+		r.Add(filmType)
+	}
+	return r
+}
+
+// Add registers filmType, a film type registered earlier with the same id is overwritten.
+func (r *FilmTypeRegistry) Add(filmType FilmType) {
+	r.filmTypes[filmType.FilmTypeId] = filmType
+}
+
+func (r *FilmTypeRegistry) Lookup(filmTypeId string) (FilmType, bool) {
+	filmType, ok := r.filmTypes[filmTypeId]
+	return filmType, ok
+}
+
+// FilmTypes returns the registered film types sorted by FilmTypeId.
+func (r *FilmTypeRegistry) FilmTypes() []FilmType {
+	filmTypes := make([]FilmType, 0, len(r.filmTypes))
+	for _, filmType := range r.filmTypes {
+		filmTypes = append(filmTypes, filmType)
+	}
+	slices.SortFunc(filmTypes, func(a, b FilmType) int {
+		return strings.Compare(a.FilmTypeId, b.FilmTypeId)
+	})
+	return filmTypes
+}
+
+// names maps every film type id to itself for Suggester.suggestIds.
+func (r *FilmTypeRegistry) names() map[string]string {
+	names := make(map[string]string, len(r.filmTypes))
+	for id := range r.filmTypes {
+		names[id] = id
+	}
+	return names
+}
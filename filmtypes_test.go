@@ -0,0 +1,126 @@
+package productmapper_test
+
+import (
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractorFilmTypes(t *testing.T) {
+	filmTypes := productmapper.NewFilmTypeRegistry(
+		productmapper.FilmType{FilmTypeId: "FG0A", Material: "TEMPERED_GLASS", Thickness: 0.33},
+		productmapper.FilmType{FilmTypeId: "FG0B", Material: "TEMPERED_GLASS", Thickness: 0.26},
+	)
+
+	tests := []struct {
+		name              string
+		mode              productmapper.Mode
+		platformProductId string
+		expected          *productmapper.ParseResult
+		err               error
+	}{
+		{
+			name:              "stray letters and digits before the film type are skipped",
+			platformProductId: "X2-3&FG0A-CLEAR-OPPOA3/20XFG0B-MATTE-OPPOA3",
+			expected: &productmapper.ParseResult{
+				Products: []productmapper.ProductParts{
+					{
+						FilmTypeId:    "FG0A",
+						TextureId:     "CLEAR",
+						ModelId:       "OPPOA3",
+						Qty:           1,
+						FilmMaterial:  "TEMPERED_GLASS",
+						FilmThickness: 0.33,
+					},
+					{
+						FilmTypeId:    "FG0B",
+						TextureId:     "MATTE",
+						ModelId:       "OPPOA3",
+						Qty:           1,
+						FilmMaterial:  "TEMPERED_GLASS",
+						FilmThickness: 0.26,
+					},
+				},
+				TotalQty: 2,
+			},
+		},
+		{
+			name:              "unknown film type is a warning in lenient mode",
+			platformProductId: "FG0C-CLEAR-OPPOA3",
+			expected: &productmapper.ParseResult{
+				Products: []productmapper.ProductParts{
+					{
+						FilmTypeId: "FG0C",
+						TextureId:  "CLEAR",
+						ModelId:    "OPPOA3",
+						Qty:        1,
+					},
+				},
+				TotalQty: 1,
+				Diagnostics: []productmapper.Diagnostic{
+					{
+						Code:        productmapper.DiagnosticUnknownFilmType,
+						Message:     "unknown film type id 'FG0C'",
+						Input:       "FG0C-CLEAR-OPPOA3",
+						Value:       "FG0C",
+						Suggestions: []string{"FG0A", "FG0B"},
+					},
+				},
+			},
+		},
+		{
+			name:              "unknown film type is an error in strict mode",
+			mode:              productmapper.ModeStrict,
+			platformProductId: "FG0A-CLEAR-OPPOA3/--FI2A-MATTE-OPPOA3",
+			err: &productmapper.ParseError{
+				Message: "unknown film type id 'FI2A'",
+				Input:   "FG0A-CLEAR-OPPOA3/--FI2A-MATTE-OPPOA3",
+				Index:   20,
+				Code:    productmapper.DiagnosticUnknownFilmType,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			extractor := productmapper.NewExtractor()
+			extractor.FilmTypes = filmTypes
+			extractor.Mode = test.mode
+
+			result, err := extractor.Parse(test.platformProductId)
+
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+
+	t.Run("film type is described on the cleaned line", func(t *testing.T) {
+		extractor := productmapper.NewExtractor()
+		extractor.FilmTypes = filmTypes
+
+		products, totalQty, err := extractor.Extract("FG0B-CLEAR-OPPOA3*2")
+		assert.NoError(t, err)
+
+		orders, err := productmapper.DiffusePrice(products, totalQty, productmapper.LineItemDetail{
+			Qty:        1,
+			UnitPrice:  100,
+			TotalPrice: 100,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []productmapper.CleanedOrder{
+			{
+				ProductId:     "FG0B-CLEAR-OPPOA3",
+				MaterialId:    "FG0B-CLEAR",
+				ModelId:       "OPPOA3",
+				TextureId:     "CLEAR",
+				FilmMaterial:  "TEMPERED_GLASS",
+				FilmThickness: 0.26,
+				Qty:           2,
+				UnitPrice:     50,
+				TotalPrice:    100,
+			},
+		}, orders)
+	})
+}
@@ -0,0 +1,131 @@
+package productmapper
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidProductParts = errors.New("invalid product parts")
+
+type FormatOptions struct {
+	// MergeDuplicates sums the quantity of products with the same ProductId,
+	// the merged product keeps the position of its first occurrence.
+	MergeDuplicates bool
+	// Sort orders products by ProductId instead of keeping the original order.
+	Sort bool
+}
+
+// CanonicalizeProducts returns a copy of products arranged as FormatPlatformId writes them.
+func CanonicalizeProducts(products []ProductParts, opts FormatOptions) ([]ProductParts, error) {
+	canonical := make([]ProductParts, 0, len(products))
+
+	if opts.MergeDuplicates {
+		indexes := map[string]int{}
+		for _, product := range products {
+			i, ok := indexes[product.ProductId()]
+			if !ok {
+				indexes[product.ProductId()] = len(canonical)
+				canonical = append(canonical, product)
+				continue
+			}
+
+			qty, ok := addQty(canonical[i].Qty, product.Qty)
+			if !ok {
+				return nil, ErrQtyOverflow
+			}
+			canonical[i].Qty = qty
+		}
+	} else {
+		canonical = append(canonical, products...)
+	}
+
+	if opts.Sort {
+		slices.SortStableFunc(canonical, func(a, b ProductParts) int {
+			return strings.Compare(a.ProductId(), b.ProductId())
+		})
+	}
+
+	return canonical, nil
+}
+
+// FormatPlatformId is the inverse of ExtractPlatformId, it writes products as
+// FilmTypeId-TextureId-ModelId joined by Splitter with a QtySymbol quantity
+// only when Qty > 1. Extracting the result gives back CanonicalizeProducts(products, opts).
+// Quantities above DefaultMaxSegmentQty per product or DefaultMaxLineQty in
+// total are rejected since NewExtractor would not extract them back.
+func FormatPlatformId(products []ProductParts, opts FormatOptions) (string, error) {
+	canonical, err := CanonicalizeProducts(products, opts)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		builder  strings.Builder
+		totalQty int
+	)
+	for i, product := range canonical {
+		if err := validateProductParts(product); err != nil {
+			return "", err
+		}
+		totalQty += product.Qty
+		if totalQty > DefaultMaxLineQty {
+			return "", fmt.Errorf("%w: total quantity exceeds maximum of %d", ErrInvalidProductParts, DefaultMaxLineQty)
+		}
+
+		if i > 0 {
+			builder.WriteRune(Splitter)
+		}
+		builder.WriteString(product.ProductId())
+		if product.Qty > 1 {
+			builder.WriteRune(QtySymbol)
+			builder.WriteString(strconv.Itoa(product.Qty))
+		}
+	}
+
+	return builder.String(), nil
+}
+
+// validateProductParts rejects products that would not be extracted back unchanged.
+func validateProductParts(product ProductParts) error {
+	if product.Qty < 1 {
+		return fmt.Errorf("%w: quantity of %s must be greater than zero", ErrInvalidProductParts, product.ProductId())
+	}
+	if product.Qty > DefaultMaxSegmentQty {
+		return fmt.Errorf("%w: quantity of %s exceeds maximum of %d", ErrInvalidProductParts, product.ProductId(), DefaultMaxSegmentQty)
+	}
+
+	var letters, digits int
+	for _, c := range product.FilmTypeId {
+		switch {
+		case unicode.IsDigit(c):
+			digits++
+		case unicode.IsLetter(c) && unicode.IsUpper(c):
+			letters++
+		default:
+			return fmt.Errorf("%w: film type id '%s' must contain only uppercase letters and digits", ErrInvalidProductParts, product.FilmTypeId)
+		}
+	}
+	if letters == 0 || digits == 0 {
+		return fmt.Errorf("%w: film type id '%s' must contain uppercase letters and digits", ErrInvalidProductParts, product.FilmTypeId)
+	}
+
+	if product.TextureId == "" || strings.IndexFunc(product.TextureId, func(c rune) bool { return !unicode.IsLetter(c) || !unicode.IsUpper(c) }) >= 0 {
+		return fmt.Errorf("%w: texture id '%s' must contain only uppercase letters", ErrInvalidProductParts, product.TextureId)
+	}
+
+	if product.ModelId == "" || strings.IndexFunc(product.ModelId, func(c rune) bool {
+		return unicode.IsSpace(c) || c == Splitter || c == QtySymbol || c == GroupOpen || c == GroupClose
+	}) >= 0 {
+		return fmt.Errorf("%w: model id '%s' must not be empty or contain spaces, '%c', '%c', '%c' or '%c'",
+			ErrInvalidProductParts, product.ModelId, Splitter, QtySymbol, GroupOpen, GroupClose)
+	}
+	if trailingTimesQty.MatchString(product.ModelId) {
+		return fmt.Errorf("%w: model id '%s' ends with a quantity", ErrInvalidProductParts, product.ModelId)
+	}
+
+	return nil
+}
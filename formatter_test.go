@@ -0,0 +1,182 @@
+package productmapper_test
+
+import (
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatPlatformId(t *testing.T) {
+	products := []productmapper.ProductParts{
+		{
+			FilmTypeId: "FG0A",
+			TextureId:  "MATTE",
+			ModelId:    "OPPOA3",
+			Qty:        1,
+		},
+		{
+			FilmTypeId: "FG0A",
+			TextureId:  "CLEAR",
+			ModelId:    "OPPOA3-B",
+			Qty:        2,
+		},
+		{
+			FilmTypeId: "FG0A",
+			TextureId:  "MATTE",
+			ModelId:    "OPPOA3",
+			Qty:        3,
+		},
+	}
+
+	tests := []struct {
+		name     string
+		products []productmapper.ProductParts
+		opts     productmapper.FormatOptions
+		expected string
+		err      error
+	}{
+		{
+			name:     "original order",
+			products: products,
+			expected: "FG0A-MATTE-OPPOA3/FG0A-CLEAR-OPPOA3-B*2/FG0A-MATTE-OPPOA3*3",
+		},
+		{
+			name:     "merge duplicates",
+			products: products,
+			opts:     productmapper.FormatOptions{MergeDuplicates: true},
+			expected: "FG0A-MATTE-OPPOA3*4/FG0A-CLEAR-OPPOA3-B*2",
+		},
+		{
+			name:     "merge duplicates and sort",
+			products: products,
+			opts:     productmapper.FormatOptions{MergeDuplicates: true, Sort: true},
+			expected: "FG0A-CLEAR-OPPOA3-B*2/FG0A-MATTE-OPPOA3*4",
+		},
+		{
+			name:     "no products",
+			products: []productmapper.ProductParts{},
+			expected: "",
+		},
+		{
+			name: "zero quantity",
+			products: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "OPPOA3",
+				},
+			},
+			err: productmapper.ErrInvalidProductParts,
+		},
+		{
+			name: "quantity above extractor maximum",
+			products: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "OPPOA3",
+					Qty:        1001,
+				},
+			},
+			err: productmapper.ErrInvalidProductParts,
+		},
+		{
+			name: "total quantity above extractor maximum",
+			products: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "OPPOA3",
+					Qty:        600,
+				},
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "MATTE",
+					ModelId:    "OPPOA3",
+					Qty:        401,
+				},
+			},
+			err: productmapper.ErrInvalidProductParts,
+		},
+		{
+			name: "texture id with digits",
+			products: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR2",
+					ModelId:    "OPPOA3",
+					Qty:        1,
+				},
+			},
+			err: productmapper.ErrInvalidProductParts,
+		},
+		{
+			name: "model id with splitter",
+			products: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "OPPOA3/B",
+					Qty:        1,
+				},
+			},
+			err: productmapper.ErrInvalidProductParts,
+		},
+		{
+			name: "model id that reads as a quantity",
+			products: []productmapper.ProductParts{
+				{
+					FilmTypeId: "FG0A",
+					TextureId:  "CLEAR",
+					ModelId:    "OPPOA3x2",
+					Qty:        1,
+				},
+			},
+			err: productmapper.ErrInvalidProductParts,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			platformProductId, err := productmapper.FormatPlatformId(test.products, test.opts)
+
+			assert.ErrorIs(t, err, test.err)
+			assert.Equal(t, test.expected, platformProductId)
+		})
+	}
+}
+
+func TestFormatPlatformIdRoundTrip(t *testing.T) {
+	platformProductIds := []string{
+		"x2-3&FG0A-MATTE-IPHONE16PROMAX*3",
+		"FG0A-CLEAR-OPPOA3/%20xFG0A-CLEAR-OPPOA3-B/FG0A-MATTE-OPPOA3",
+		"2x FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3 4pcs",
+		"((FG0A-CLEAR-OPPOA3*2/FG0A-MATTE-OPPOA3) x2/FG0A-PRIVACY-OPPOA3)*3/FG0B-CLEAR-OPPOA3",
+		"FG0A-CLEAR-GALAXYX2*2/FG0A-CLEAR-GALAXYX2",
+		"FG0A-CLEAR-OPPOA3*1000",
+		"FG0A-CLEAR-OPPOA3*500/FG0A-CLEAR-OPPOA3*500",
+	}
+
+	for _, opts := range []productmapper.FormatOptions{
+		{},
+		{MergeDuplicates: true},
+		{MergeDuplicates: true, Sort: true},
+	} {
+		for _, platformProductId := range platformProductIds {
+			products, totalQty, err := productmapper.ExtractPlatformId(platformProductId)
+			assert.NoError(t, err)
+
+			formatted, err := productmapper.FormatPlatformId(products, opts)
+			assert.NoError(t, err)
+
+			canonical, err := productmapper.CanonicalizeProducts(products, opts)
+			assert.NoError(t, err)
+
+			reparsed, reparsedTotalQty, err := productmapper.ExtractPlatformId(formatted)
+			assert.NoError(t, err)
+			assert.Equal(t, canonical, reparsed, "round trip of %s through %s", platformProductId, formatted)
+			assert.Equal(t, totalQty, reparsedTotalQty)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package productmapper
+
+import (
+	"context"
+	"log/slog"
+)
+
+// logAttrs logs to logger when it is not nil, logging is optional everywhere.
+func logAttrs(ctx context.Context, logger *slog.Logger, level slog.Level, msg string, attrs ...slog.Attr) {
+	if logger == nil {
+		return
+	}
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,261 @@
+package productmapper
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"time"
+)
+
+// Mapper cleans marketplace orders. Build it once with NewMapper and reuse
+// it, Clean does not change the Mapper and is safe for concurrent use.
+type Mapper struct {
+	extractor          *Extractor
+	diffuser           *Diffuser
+	complementaryItems []ComplementaryItem
+	strategies         map[string]ComplementaryStrategy
+	catalog            Catalog
+	discountBasis      ProrationBasis
+	feeBasis           ProrationBasis
+	feeWeight          WeightFunc
+	logger             *slog.Logger
+	metrics            Metrics
+	tracer             Tracer
+	stages             []Stage
+	// err is a misconfiguration returned by Clean, e.g. an ErrUnknownStage.
+	err error
+}
+
+type Option func(*mapperConfig)
+
+// mapperConfig collects the options, the mode and explain options apply to
+// the parser and allocator whichever order they are given in.
+type mapperConfig struct {
+	Mapper
+	mode       *Mode
+	explain    bool
+	insertions []stageInsertion
+}
+
+// WithParser sets the Extractor that parses the platform product ids,
+// NewExtractor by default or when extractor is nil.
+func WithParser(extractor *Extractor) Option {
+	return func(c *mapperConfig) {
+		if extractor == nil {
+			extractor = NewExtractor()
+		}
+		c.extractor = extractor
+	}
+}
+
+// WithAllocator sets the Diffuser that splits the line prices over the
+// products, NewDiffuser by default or when diffuser is nil.
+func WithAllocator(diffuser *Diffuser) Option {
+	return func(c *mapperConfig) {
+		if diffuser == nil {
+			diffuser = NewDiffuser()
+		}
+		c.diffuser = diffuser
+	}
+}
+
+// WithComplementaryItems sets the complementary items given with the products.
+func WithComplementaryItems(items ...ComplementaryItem) Option {
+	return func(c *mapperConfig) {
+		c.complementaryItems = items
+	}
+}
+
+// WithComplementaryStrategy registers the strategy of a logical
+// ComplementaryItem.Type, replacing a built-in one of the same type.
+func WithComplementaryStrategy(itemType string, strategy ComplementaryStrategy) Option {
+	return func(c *mapperConfig) {
+		c.strategies[itemType] = strategy
+	}
+}
+
+// WithCatalog checks the cleaned lines against catalog, see CheckCatalog.
+func WithCatalog(catalog Catalog) Option {
+	return func(c *mapperConfig) {
+		c.catalog = catalog
+	}
+}
+
+// WithDiscountBasis sets how CleanWithHeader prorates the header discounts,
+// ProrateByValue by default.
+func WithDiscountBasis(basis ProrationBasis) Option {
+	return func(c *mapperConfig) {
+		c.discountBasis = basis
+	}
+}
+
+// WithFeeBasis sets how CleanWithHeader allocates the header fees,
+// ProrateByValue by default. weight is only used with ProrateByWeight.
+func WithFeeBasis(basis ProrationBasis, weight WeightFunc) Option {
+	return func(c *mapperConfig) {
+		c.feeBasis = basis
+		c.feeWeight = weight
+	}
+}
+
+// WithLogger logs the pipeline to logger with the order and line attributes,
+// replacing the Logger of the parser and allocator.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *mapperConfig) {
+		c.logger = logger
+	}
+}
+
+// WithMetrics counts the orders, parse errors, complementary items and
+// latency of Clean in metrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(c *mapperConfig) {
+		if metrics == nil {
+			metrics = noopMetrics{}
+		}
+		c.metrics = metrics
+	}
+}
+
+// WithTracer starts a span around Clean, every stage and every order within
+// the extract and diffuse stages in tracer, as children of the span in the
+// context given to Clean.
+func WithTracer(tracer Tracer) Option {
+	return func(c *mapperConfig) {
+		if tracer == nil {
+			tracer = noopTracer{}
+		}
+		c.tracer = tracer
+	}
+}
+
+// WithMode sets the Mode of the parser and the catalog check, overriding the
+// Mode of the Extractor given to WithParser.
+func WithMode(mode Mode) Option {
+	return func(c *mapperConfig) {
+		c.mode = &mode
+	}
+}
+
+// WithStrict is WithMode(ModeStrict).
+func WithStrict() Option {
+	return WithMode(ModeStrict)
+}
+
+// WithExplain records a PriceTrace on every diffused line.
+func WithExplain() Option {
+	return func(c *mapperConfig) {
+		c.explain = true
+	}
+}
+
+func NewMapper(opts ...Option) *Mapper {
+	c := &mapperConfig{
+		Mapper: Mapper{
+			extractor:  NewExtractor(),
+			diffuser:   NewDiffuser(),
+			strategies: DefaultComplementaryStrategies(),
+			metrics:    noopMetrics{},
+			tracer:     noopTracer{},
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// copy the parser and allocator so the options do not change the given ones
+	if c.mode != nil {
+		extractor := *c.extractor
+		extractor.Mode = *c.mode
+		c.extractor = &extractor
+	}
+	if c.explain {
+		diffuser := *c.diffuser
+		diffuser.Explain = true
+		c.diffuser = &diffuser
+	}
+	c.strategies = maps.Clone(c.strategies)
+
+	c.stages, c.err = insertStages([]Stage{
+		&extractStage{extractor: c.extractor},
+		&diffuseStage{diffuser: c.diffuser},
+		&prorateStage{discountBasis: c.discountBasis, feeBasis: c.feeBasis, feeWeight: c.feeWeight},
+		&complementaryStage{items: c.complementaryItems, strategies: c.strategies},
+		&catalogStage{catalog: c.catalog, mode: c.extractor.Mode},
+	}, c.insertions)
+
+	m := c.Mapper
+	return &m
+}
+
+// Stages returns the names of the stages Clean runs, in order.
+func (m *Mapper) Stages() []string {
+	names := make([]string, len(m.stages))
+	for i, stage := range m.stages {
+		names[i] = stage.Name()
+	}
+	return names
+}
+
+// CleanResult is the outcome of Mapper.Clean.
+type CleanResult struct {
+	Orders []CleanedOrder
+	// Diagnostics are the parse and price problems that did not stop the
+	// cleaning, their Input is the platform product id of the order.
+	Diagnostics []Diagnostic
+	// CatalogIssues are the lines not in the catalog, see WithCatalog.
+	CatalogIssues []CatalogIssue
+}
+
+// Clean runs the stages on orders: by default it extracts the products of
+// every order, diffuses the order price over them, adds the complementary
+// items and checks the catalog.
+func (m *Mapper) Clean(ctx context.Context, orders []InputOrder) (*CleanResult, error) {
+	return m.clean(ctx, nil, orders)
+}
+
+// CleanWithHeader is Clean for the lines of a single marketplace order, the
+// prorate stage spreads the discounts and fees of header over the diffused
+// lines before the complementary items are added.
+func (m *Mapper) CleanWithHeader(ctx context.Context, header OrderHeader, orders []InputOrder) (*CleanResult, error) {
+	return m.clean(ctx, &header, orders)
+}
+
+func (m *Mapper) clean(ctx context.Context, header *OrderHeader, orders []InputOrder) (_ *CleanResult, err error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	start := time.Now()
+	ctx, span := m.tracer.Start(ctx, SpanClean, slog.Int("orders", len(orders)))
+	defer func() {
+		endSpan(span, err)
+		m.metrics.ObserveLatency(time.Since(start))
+	}()
+
+	oc := &OrderContext{
+		Lines:   make([]*OrderLine, len(orders)),
+		Header:  header,
+		Logger:  m.logger,
+		Metrics: m.metrics,
+		Tracer:  m.tracer,
+	}
+	for i, order := range orders {
+		oc.Lines[i] = &OrderLine{Input: order}
+	}
+
+	for _, stage := range m.stages {
+		stageCtx, stageSpan := m.tracer.Start(ctx, spanName(stage.Name()))
+		err := stage.Process(stageCtx, oc)
+		endSpan(stageSpan, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &CleanResult{
+		Orders:        oc.Orders,
+		Diagnostics:   oc.Diagnostics,
+		CatalogIssues: oc.CatalogIssues,
+	}, nil
+}
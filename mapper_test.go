@@ -0,0 +1,277 @@
+package productmapper_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapper(t *testing.T) {
+	orders := []productmapper.InputOrder{
+		{
+			No:                1,
+			PlatformProductId: "FG0A-CLEAR-OPPOA3/FG0A-MATE-OPPOA3*2",
+			Qty:               1,
+			UnitPrice:         100,
+			TotalPrice:        100,
+		},
+	}
+
+	newParser := func() *productmapper.Extractor {
+		extractor := productmapper.NewExtractor()
+		extractor.Textures = productmapper.DefaultTextureRegistry()
+		return extractor
+	}
+
+	t.Run("lenient mode reports diagnostics", func(t *testing.T) {
+		result, err := productmapper.NewMapper(
+			productmapper.WithParser(newParser()),
+			productmapper.WithComplementaryItems(productmapper.ComplementaryItem{ProductId: "WIPING-CLOTH", PerQty: 1}),
+		).Clean(context.Background(), orders)
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Orders, 3)
+		assert.Equal(t, productmapper.CleanedOrder{No: 3, ProductId: "WIPING-CLOTH", Qty: 3}, result.Orders[2])
+		assert.Equal(t, []productmapper.Diagnostic{
+			{
+				Code:        productmapper.DiagnosticUnknownTexture,
+				Message:     "unknown texture id 'MATE'",
+				Input:       "FG0A-CLEAR-OPPOA3/FG0A-MATE-OPPOA3*2",
+				Index:       23,
+				Value:       "MATE",
+				Suggestions: []string{"MATTE"},
+			},
+		}, result.Diagnostics)
+	})
+
+	t.Run("strict mode overrides the parser mode", func(t *testing.T) {
+		parser := newParser()
+
+		_, err := productmapper.NewMapper(productmapper.WithStrict(), productmapper.WithParser(parser)).Clean(context.Background(), orders)
+
+		assert.Equal(t, &productmapper.ParseError{
+			Message:     "unknown texture id 'MATE'",
+			Input:       "FG0A-CLEAR-OPPOA3/FG0A-MATE-OPPOA3*2",
+			Index:       23,
+			Suggestions: []string{"MATTE"},
+			Code:        productmapper.DiagnosticUnknownTexture,
+		}, err)
+		assert.Equal(t, productmapper.ModeLenient, parser.Mode, "the given parser is not modified")
+	})
+
+	t.Run("allocator diagnostics", func(t *testing.T) {
+		result, err := productmapper.NewMapper(
+			productmapper.WithAllocator(&productmapper.Diffuser{Floors: map[string]float64{"FG0A-CLEAR": 50}}),
+			productmapper.WithExplain(),
+		).Clean(context.Background(), []productmapper.InputOrder{
+			{
+				No:                1,
+				PlatformProductId: "FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3*2",
+				Qty:               1,
+				UnitPrice:         80,
+				TotalPrice:        90,
+			},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []float64{50, 20}, []float64{result.Orders[0].UnitPrice, result.Orders[1].UnitPrice})
+		assert.Equal(t, 50.0, result.Orders[0].Trace.Floor)
+		assert.Equal(t, []productmapper.Diagnostic{
+			{
+				Code:    productmapper.DiagnosticPriceSurcharge,
+				Message: "total price 90 exceeds unit price 80 * qty 1",
+				Input:   "FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3*2",
+				Value:   "10",
+			},
+		}, result.Diagnostics)
+	})
+
+	t.Run("nil parser and allocator fall back to the defaults", func(t *testing.T) {
+		mapper := productmapper.NewMapper(productmapper.WithParser(nil), productmapper.WithAllocator(nil))
+
+		result, err := mapper.Clean(context.Background(), []productmapper.InputOrder{
+			{No: 1, PlatformProductId: "FG0A-CLEAR-OPPOA3*2", Qty: 1, UnitPrice: 100, TotalPrice: 100},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 50.0, result.Orders[0].UnitPrice)
+		_, err = mapper.Refund(context.Background(), productmapper.InputOrder{No: 1, PlatformProductId: "FG0A-CLEAR-OPPOA3", Qty: 1, UnitPrice: 100, TotalPrice: 100}, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("free products marked per line", func(t *testing.T) {
+		result, err := productmapper.NewMapper().Clean(context.Background(), []productmapper.InputOrder{
+			{
+				No:                1,
+				PlatformProductId: "FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3",
+				Qty:               1,
+				UnitPrice:         100,
+				TotalPrice:        100,
+				FreeProducts:      []string{"FG0A-MATTE"},
+			},
+			{
+				No:                2,
+				PlatformProductId: "FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3",
+				Qty:               1,
+				UnitPrice:         100,
+				TotalPrice:        100,
+			},
+		})
+
+		assert.NoError(t, err)
+		var totalPrices []float64
+		for _, order := range result.Orders {
+			totalPrices = append(totalPrices, order.TotalPrice)
+		}
+		assert.Equal(t, []float64{100, 0, 50, 50}, totalPrices)
+	})
+
+	t.Run("custom complementary strategy", func(t *testing.T) {
+		result, err := productmapper.NewMapper(
+			productmapper.WithComplementaryItems(
+				productmapper.ComplementaryItem{ProductId: "CASE", PerQty: 1, Type: "SUFFIX_MODEL"},
+				productmapper.ComplementaryItem{ProductId: "CLEANNER", PerQty: 1, Type: productmapper.ComplementarySuffixTexture},
+			),
+			productmapper.WithComplementaryStrategy("SUFFIX_MODEL", func(order productmapper.CleanedOrder, item productmapper.ComplementaryItem) string {
+				return item.ProductId + "-" + order.ModelId
+			}),
+		).Clean(context.Background(), []productmapper.InputOrder{
+			{
+				No:                1,
+				PlatformProductId: "FG0A-CLEAR-OPPOA3/FG0A-MATTE-IPHONE16",
+				Qty:               1,
+				UnitPrice:         100,
+				TotalPrice:        100,
+			},
+		})
+
+		assert.NoError(t, err)
+		var complementary []string
+		for _, order := range result.Orders[2:] {
+			complementary = append(complementary, order.ProductId)
+		}
+		assert.Equal(t, []string{"CASE-OPPOA3", "CLEAR-CLEANNER", "CASE-IPHONE16", "MATTE-CLEANNER"}, complementary)
+	})
+
+	t.Run("catalog", func(t *testing.T) {
+		catalog, err := productmapper.LoadCatalog(strings.NewReader(catalogCSV))
+		assert.NoError(t, err)
+		orders := []productmapper.InputOrder{
+			{
+				No:                1,
+				PlatformProductId: "FG0A-CLEAR-OPPOA3/FG0A-PRIVACY-OPPOA3",
+				Qty:               1,
+				UnitPrice:         80,
+				TotalPrice:        80,
+			},
+		}
+
+		result, err := productmapper.NewMapper(productmapper.WithCatalog(catalog)).Clean(context.Background(), orders)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []productmapper.CatalogIssue{
+			{No: 2, ProductId: "FG0A-PRIVACY-OPPOA3", Field: "ProductId", Value: "FG0A-PRIVACY-OPPOA3"},
+			{No: 2, ProductId: "FG0A-PRIVACY-OPPOA3", Field: "MaterialId", Value: "FG0A-PRIVACY"},
+		}, result.CatalogIssues)
+
+		_, err = productmapper.NewMapper(productmapper.WithCatalog(catalog), productmapper.WithStrict()).Clean(context.Background(), orders)
+
+		assert.ErrorIs(t, err, productmapper.ErrNotInCatalog)
+	})
+}
+
+func TestMapperLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	parser := productmapper.NewExtractor()
+	parser.Textures = productmapper.DefaultTextureRegistry()
+
+	_, err := productmapper.NewMapper(
+		productmapper.WithLogger(logger),
+		productmapper.WithParser(parser),
+		productmapper.WithAllocator(&productmapper.Diffuser{Floors: map[string]float64{"FG0A-CLEAR": 60}}),
+		productmapper.WithComplementaryItems(productmapper.ComplementaryItem{ProductId: "WIPING-CLOTH", PerQty: 1}),
+	).Clean(context.Background(), []productmapper.InputOrder{
+		{
+			No:                7,
+			PlatformProductId: "x2-3&FG0A-CLEAR-OPPOA3/FG0A-MATE-OPPOA3",
+			Qty:               1,
+			UnitPrice:         100,
+			TotalPrice:        100,
+		},
+	})
+	assert.NoError(t, err)
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+
+	assert.Equal(t, []map[string]any{
+		{
+			"level":               "DEBUG",
+			"msg":                 "skipped characters",
+			"order_no":            7.0,
+			"platform_product_id": "x2-3&FG0A-CLEAR-OPPOA3/FG0A-MATE-OPPOA3",
+			"skipped":             "x2-3&",
+			"reason":              "before film type id",
+			"input":               "x2-3&FG0A-CLEAR-OPPOA3/FG0A-MATE-OPPOA3",
+			"index":               0.0,
+		},
+		{
+			"level":               "WARN",
+			"msg":                 "unknown texture id 'MATE'",
+			"order_no":            7.0,
+			"platform_product_id": "x2-3&FG0A-CLEAR-OPPOA3/FG0A-MATE-OPPOA3",
+			"code":                "UNKNOWN_TEXTURE",
+			"input":               "x2-3&FG0A-CLEAR-OPPOA3/FG0A-MATE-OPPOA3",
+			"index":               28.0,
+			"suggestions":         []any{"MATTE"},
+		},
+		{
+			"level":               "INFO",
+			"msg":                 "unit price raised to floor",
+			"order_no":            7.0,
+			"platform_product_id": "x2-3&FG0A-CLEAR-OPPOA3/FG0A-MATE-OPPOA3",
+			"product_id":          "FG0A-CLEAR-OPPOA3",
+			"unit_price":          50.0,
+			"floor":               60.0,
+		},
+		{
+			"level":           "DEBUG",
+			"msg":             "complementary item",
+			"line_no":         1.0,
+			"line_product_id": "FG0A-CLEAR-OPPOA3",
+			"rule_product_id": "WIPING-CLOTH",
+			"rule_type":       "",
+			"product_id":      "WIPING-CLOTH",
+			"qty":             1.0,
+		},
+		{
+			"level":           "DEBUG",
+			"msg":             "complementary item",
+			"line_no":         2.0,
+			"line_product_id": "FG0A-MATE-OPPOA3",
+			"rule_product_id": "WIPING-CLOTH",
+			"rule_type":       "",
+			"product_id":      "WIPING-CLOTH",
+			"qty":             1.0,
+		},
+	}, records)
+}
@@ -0,0 +1,77 @@
+package productmapper
+
+import (
+	"maps"
+	"slices"
+)
+
+// MergeDuplicates consolidates orders with the same ProductId, LineType,
+// Currency and ReportingCurrency into the first of them: Qty, the prices,
+// Discount, Fees and the tax and reporting amounts are summed, UnitPrice and
+// ReportingUnitPrice become the quantity weighted averages and SourceNos holds
+// the source lines of every merged order. A merged order has no Trace since a
+// PriceTrace explains a single allocation. When the orders are numbered, No is
+// renumbered from 1.
+func MergeDuplicates(orders []CleanedOrder) ([]CleanedOrder, error) {
+	type key struct {
+		productId         string
+		lineType          LineType
+		currency          string
+		reportingCurrency string
+	}
+
+	merged := []CleanedOrder{}
+	indexes := map[key]int{}
+	numbered := false
+
+	for _, order := range orders {
+		numbered = numbered || order.No != 0
+
+		k := key{productId: order.ProductId, lineType: order.LineType, currency: order.Currency, reportingCurrency: order.ReportingCurrency}
+		i, ok := indexes[k]
+		if !ok {
+			indexes[k] = len(merged)
+			order.SourceNos = slices.Clone(order.SourceNos)
+			order.Fees = maps.Clone(order.Fees)
+			merged = append(merged, order)
+			continue
+		}
+
+		qty, ok := addQty(merged[i].Qty, order.Qty)
+		if !ok {
+			return nil, ErrQtyOverflow
+		}
+		merged[i].Qty = qty
+		merged[i].TotalPrice += order.TotalPrice
+		merged[i].ReportingTotalPrice += order.ReportingTotalPrice
+		if qty != 0 {
+			merged[i].UnitPrice = merged[i].TotalPrice / float64(qty)
+			merged[i].ReportingUnitPrice = merged[i].ReportingTotalPrice / float64(qty)
+		}
+		merged[i].Discount += order.Discount
+		merged[i].NetAmount += order.NetAmount
+		merged[i].TaxAmount += order.TaxAmount
+		merged[i].GrossAmount += order.GrossAmount
+		for feeType, fee := range order.Fees {
+			if merged[i].Fees == nil {
+				merged[i].Fees = map[string]float64{}
+			}
+			merged[i].Fees[feeType] += fee
+		}
+		merged[i].Trace = nil
+
+		for _, sourceNo := range order.SourceNos {
+			if !slices.Contains(merged[i].SourceNos, sourceNo) {
+				merged[i].SourceNos = append(merged[i].SourceNos, sourceNo)
+			}
+		}
+	}
+
+	if numbered {
+		for i := range merged {
+			merged[i].No = i + 1
+		}
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,147 @@
+package productmapper_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeDuplicates(t *testing.T) {
+	t.Run("same product on two marketplace lines", func(t *testing.T) {
+		orders, err := productmapper.CleanOrder(context.Background(), []productmapper.InputOrder{
+			{
+				No:                1,
+				PlatformProductId: "FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3",
+				Qty:               1,
+				UnitPrice:         80,
+				TotalPrice:        80,
+			},
+			{
+				No:                2,
+				PlatformProductId: "FG0A-CLEAR-OPPOA3*2",
+				Qty:               1,
+				UnitPrice:         100,
+				TotalPrice:        100,
+			},
+		}, []productmapper.ComplementaryItem{
+			{
+				ProductId: "WIPING-CLOTH",
+				PerQty:    1,
+			},
+		})
+		assert.NoError(t, err)
+
+		merged, err := productmapper.MergeDuplicates(orders)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []productmapper.CleanedOrder{
+			{
+				No:         1,
+				ProductId:  "FG0A-CLEAR-OPPOA3",
+				MaterialId: "FG0A-CLEAR",
+				ModelId:    "OPPOA3",
+				TextureId:  "CLEAR",
+				Qty:        3,
+				UnitPrice:  46.666666666666664,
+				TotalPrice: 140,
+				SourceNos:  []int{1, 2},
+			},
+			{
+				No:         2,
+				ProductId:  "FG0A-MATTE-OPPOA3",
+				MaterialId: "FG0A-MATTE",
+				ModelId:    "OPPOA3",
+				TextureId:  "MATTE",
+				Qty:        1,
+				UnitPrice:  40,
+				TotalPrice: 40,
+				SourceNos:  []int{1},
+			},
+			{
+				No:        3,
+				ProductId: "WIPING-CLOTH",
+				Qty:       4,
+			},
+		}, merged)
+		assert.Equal(t, []int{1}, orders[0].SourceNos, "input orders are not modified")
+	})
+
+	t.Run("kit and component lines with the same product id are not merged", func(t *testing.T) {
+		orders := []productmapper.CleanedOrder{
+			{ProductId: "DUST-STICKER", Qty: 1, UnitPrice: 5, TotalPrice: 5},
+			{ProductId: "DUST-STICKER", Qty: 2, UnitPrice: 1, TotalPrice: 2, LineType: productmapper.LineComponent},
+			{ProductId: "DUST-STICKER", Qty: 2, UnitPrice: 1.5, TotalPrice: 3, LineType: productmapper.LineComponent},
+		}
+
+		merged, err := productmapper.MergeDuplicates(orders)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []productmapper.CleanedOrder{
+			{ProductId: "DUST-STICKER", Qty: 1, UnitPrice: 5, TotalPrice: 5},
+			{ProductId: "DUST-STICKER", Qty: 4, UnitPrice: 1.25, TotalPrice: 5, LineType: productmapper.LineComponent},
+		}, merged)
+	})
+
+	t.Run("order level amounts are summed", func(t *testing.T) {
+		orders := []productmapper.CleanedOrder{
+			{No: 1, ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1, UnitPrice: 100, TotalPrice: 100, Currency: "MYR", SourceNos: []int{1}},
+			{No: 2, ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1, UnitPrice: 100, TotalPrice: 100, Currency: "MYR", SourceNos: []int{2}},
+		}
+		header := productmapper.OrderHeader{
+			Discounts: []productmapper.OrderDiscount{{Type: "SHOP_VOUCHER", Amount: 50}},
+			Fees:      []productmapper.OrderFee{{Type: "SHIPPING", Amount: 20}},
+		}
+		rates := productmapper.NewStaticRates()
+		assert.NoError(t, rates.Set("MYR", "THB", 7.5))
+
+		orders, err := productmapper.ProrateDiscounts(orders, header, productmapper.ProrateByValue)
+		assert.NoError(t, err)
+		orders, err = productmapper.AllocateFees(orders, header, productmapper.ProrateByValue, nil)
+		assert.NoError(t, err)
+		orders, err = productmapper.ApplyTax(orders, productmapper.DefaultTaxConfig())
+		assert.NoError(t, err)
+		orders, err = productmapper.ConvertCurrency(context.Background(), orders, rates, "THB")
+		assert.NoError(t, err)
+
+		merged, err := productmapper.MergeDuplicates(orders)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []productmapper.CleanedOrder{
+			{
+				No:                  1,
+				ProductId:           "FG0A-CLEAR-OPPOA3",
+				Qty:                 2,
+				UnitPrice:           100,
+				TotalPrice:          200,
+				Currency:            "MYR",
+				ReportingCurrency:   "THB",
+				ExchangeRate:        7.5,
+				ReportingUnitPrice:  750,
+				ReportingTotalPrice: 1500,
+				Discount:            50,
+				Fees:                map[string]float64{"SHIPPING": 20},
+				NetAmount:           140.18,
+				TaxAmount:           9.82,
+				GrossAmount:         150,
+				SourceNos:           []int{1, 2},
+			},
+		}, merged)
+		assert.Equal(t, map[string]float64{"SHIPPING": 10}, orders[0].Fees, "input orders are not modified")
+	})
+
+	t.Run("traces are dropped from merged lines", func(t *testing.T) {
+		orders := []productmapper.CleanedOrder{
+			{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1, UnitPrice: 100, TotalPrice: 100, Trace: &productmapper.PriceTrace{LineTotalPrice: 100}},
+			{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1, UnitPrice: 100, TotalPrice: 100, Trace: &productmapper.PriceTrace{LineTotalPrice: 100}},
+			{ProductId: "FG0A-MATTE-OPPOA3", Qty: 1, UnitPrice: 100, TotalPrice: 100, Trace: &productmapper.PriceTrace{LineTotalPrice: 100}},
+		}
+
+		merged, err := productmapper.MergeDuplicates(orders)
+
+		assert.NoError(t, err)
+		assert.Nil(t, merged[0].Trace)
+		assert.Equal(t, orders[2].Trace, merged[1].Trace)
+	})
+}
@@ -0,0 +1,186 @@
+package productmapper
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParseErrorCode is the code parse errors without a DiagnosticCode are counted under.
+const ParseErrorCode = "PARSE_ERROR"
+
+// Metrics receives the counters of Mapper.Clean, see WithMetrics.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// OrdersProcessed counts the InputOrders cleaned.
+	OrdersProcessed(n int)
+	// ParseError counts a parse error or parse diagnostic by its code.
+	ParseError(code string)
+	// ComplementaryItems counts the complementary items given by a rule, see ComplementaryRule.
+	ComplementaryItems(rule string, qty int)
+	// ObserveLatency records how long a Mapper.Clean call took.
+	ObserveLatency(d time.Duration)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) OrdersProcessed(int)            {}
+func (noopMetrics) ParseError(string)              {}
+func (noopMetrics) ComplementaryItems(string, int) {}
+func (noopMetrics) ObserveLatency(time.Duration)   {}
+
+// ComplementaryRule names a complementary item in metrics, its ProductId
+// prefixed by its Type for logical items, e.g. SUFFIX_TEXTURE:CLEANNER.
+func ComplementaryRule(item ComplementaryItem) string {
+	if item.Type == "" {
+		return item.ProductId
+	}
+	return item.Type + ":" + item.ProductId
+}
+
+// parseErrorCode returns the code err is counted under.
+func parseErrorCode(err error) string {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) && parseErr.Code != "" {
+		return string(parseErr.Code)
+	}
+	return ParseErrorCode
+}
+
+// DefaultLatencyBuckets are the upper bounds in seconds of the latency histogram.
+var DefaultLatencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// PrometheusMetrics is a Metrics that keeps its counters in memory and writes
+// them in the Prometheus text exposition format, e.g. for a /metrics handler
+// or a node exporter textfile.
+type PrometheusMetrics struct {
+	// Namespace prefixes the metric names, "productmapper" by default.
+	Namespace string
+	// Buckets are the upper bounds in seconds of the latency histogram, they
+	// must not change once a latency is observed.
+	Buckets []float64
+
+	mu                 sync.Mutex
+	orders             int64
+	parseErrors        map[string]int64
+	complementaryItems map[string]int64
+	latencyCounts      []int64
+	latencySum         float64
+	latencyCount       int64
+}
+
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		Namespace: "productmapper",
+		Buckets:   DefaultLatencyBuckets,
+	}
+}
+
+func (m *PrometheusMetrics) OrdersProcessed(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.orders += int64(n)
+}
+
+func (m *PrometheusMetrics) ParseError(code string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.parseErrors == nil {
+		m.parseErrors = map[string]int64{}
+	}
+	m.parseErrors[code]++
+}
+
+func (m *PrometheusMetrics) ComplementaryItems(rule string, qty int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.complementaryItems == nil {
+		m.complementaryItems = map[string]int64{}
+	}
+	m.complementaryItems[rule] += int64(qty)
+}
+
+func (m *PrometheusMetrics) ObserveLatency(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.latencyCounts == nil {
+		m.latencyCounts = make([]int64, len(m.Buckets))
+	}
+	seconds := d.Seconds()
+	for i, bound := range m.Buckets {
+		if seconds <= bound {
+			m.latencyCounts[i]++
+		}
+	}
+	m.latencySum += seconds
+	m.latencyCount++
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	name := func(metric string) string {
+		if m.Namespace == "" {
+			return metric
+		}
+		return m.Namespace + "_" + metric
+	}
+
+	orders := name("orders_processed_total")
+	fmt.Fprintf(&b, "# HELP %s Orders cleaned.\n# TYPE %s counter\n", orders, orders)
+	fmt.Fprintf(&b, "%s %d\n", orders, m.orders)
+
+	parseErrors := name("parse_errors_total")
+	fmt.Fprintf(&b, "# HELP %s Parse errors and diagnostics by code.\n# TYPE %s counter\n", parseErrors, parseErrors)
+	writeLabeled(&b, parseErrors, "code", m.parseErrors)
+
+	complementaryItems := name("complementary_items_total")
+	fmt.Fprintf(&b, "# HELP %s Complementary items given by rule.\n# TYPE %s counter\n", complementaryItems, complementaryItems)
+	writeLabeled(&b, complementaryItems, "rule", m.complementaryItems)
+
+	latency := name("clean_duration_seconds")
+	fmt.Fprintf(&b, "# HELP %s Duration of Mapper.Clean calls.\n# TYPE %s histogram\n", latency, latency)
+	for i, bound := range m.Buckets {
+		var count int64
+		if m.latencyCounts != nil {
+			count = m.latencyCounts[i]
+		}
+		fmt.Fprintf(&b, "%s_bucket{le=\"%s\"} %d\n", latency, formatFloat(bound), count)
+	}
+	fmt.Fprintf(&b, "%s_bucket{le=\"+Inf\"} %d\n", latency, m.latencyCount)
+	fmt.Fprintf(&b, "%s_sum %s\n", latency, formatFloat(m.latencySum))
+	fmt.Fprintf(&b, "%s_count %d\n", latency, m.latencyCount)
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeLabeled writes a sample per label value, sorted by label value.
+func writeLabeled(b *strings.Builder, metric string, label string, values map[string]int64) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		fmt.Fprintf(b, "%s{%s=\"%s\"} %d\n", metric, label, labelEscaper.Replace(key), values[key])
+	}
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
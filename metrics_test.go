@@ -0,0 +1,91 @@
+package productmapper_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	t.Run("text exposition", func(t *testing.T) {
+		metrics := productmapper.NewPrometheusMetrics()
+		metrics.Buckets = []float64{0.001, 0.01}
+
+		metrics.OrdersProcessed(3)
+		metrics.ParseError("UNKNOWN_TEXTURE")
+		metrics.ParseError(productmapper.ParseErrorCode)
+		metrics.ParseError("UNKNOWN_TEXTURE")
+		metrics.ComplementaryItems("WIPING-CLOTH", 4)
+		metrics.ComplementaryItems(`SUFFIX_TEXTURE:"CLEANNER"`, 1)
+		metrics.ObserveLatency(500 * time.Microsecond)
+		metrics.ObserveLatency(5 * time.Millisecond)
+		metrics.ObserveLatency(time.Second)
+
+		var b strings.Builder
+		_, err := metrics.WriteTo(&b)
+
+		assert.NoError(t, err)
+		assert.Equal(t, `# HELP productmapper_orders_processed_total Orders cleaned.
+# TYPE productmapper_orders_processed_total counter
+productmapper_orders_processed_total 3
+# HELP productmapper_parse_errors_total Parse errors and diagnostics by code.
+# TYPE productmapper_parse_errors_total counter
+productmapper_parse_errors_total{code="PARSE_ERROR"} 1
+productmapper_parse_errors_total{code="UNKNOWN_TEXTURE"} 2
+# HELP productmapper_complementary_items_total Complementary items given by rule.
+# TYPE productmapper_complementary_items_total counter
+productmapper_complementary_items_total{rule="SUFFIX_TEXTURE:\"CLEANNER\""} 1
+productmapper_complementary_items_total{rule="WIPING-CLOTH"} 4
+# HELP productmapper_clean_duration_seconds Duration of Mapper.Clean calls.
+# TYPE productmapper_clean_duration_seconds histogram
+productmapper_clean_duration_seconds_bucket{le="0.001"} 1
+productmapper_clean_duration_seconds_bucket{le="0.01"} 2
+productmapper_clean_duration_seconds_bucket{le="+Inf"} 3
+productmapper_clean_duration_seconds_sum 1.0055
+productmapper_clean_duration_seconds_count 3
+`, b.String())
+	})
+
+	t.Run("mapper", func(t *testing.T) {
+		metrics := productmapper.NewPrometheusMetrics()
+		parser := productmapper.NewExtractor()
+		parser.Textures = productmapper.DefaultTextureRegistry()
+		mapper := productmapper.NewMapper(
+			productmapper.WithMetrics(metrics),
+			productmapper.WithParser(parser),
+			productmapper.WithComplementaryItems(
+				productmapper.ComplementaryItem{ProductId: "WIPING-CLOTH", PerQty: 1},
+				productmapper.ComplementaryItem{ProductId: "CLEANNER", PerQty: 1, Type: productmapper.ComplementarySuffixTexture},
+			),
+		)
+
+		_, err := mapper.Clean(context.Background(), []productmapper.InputOrder{
+			{No: 1, PlatformProductId: "FG0A-CLEAR-OPPOA3*2", Qty: 1, UnitPrice: 100, TotalPrice: 100},
+			{No: 2, PlatformProductId: "FG0A-MATE-OPPOA3", Qty: 1, UnitPrice: 50, TotalPrice: 50},
+		})
+		assert.NoError(t, err)
+		_, err = mapper.Clean(context.Background(), []productmapper.InputOrder{
+			{No: 1, PlatformProductId: "FG0A-CLEAR-OPPOA3**", Qty: 1, UnitPrice: 100, TotalPrice: 100},
+		})
+		assert.Error(t, err)
+
+		var b strings.Builder
+		_, err = metrics.WriteTo(&b)
+		assert.NoError(t, err)
+
+		for _, sample := range []string{
+			"productmapper_orders_processed_total 2\n",
+			`productmapper_parse_errors_total{code="PARSE_ERROR"} 1` + "\n",
+			`productmapper_parse_errors_total{code="UNKNOWN_TEXTURE"} 1` + "\n",
+			`productmapper_complementary_items_total{rule="SUFFIX_TEXTURE:CLEANNER"} 3` + "\n",
+			`productmapper_complementary_items_total{rule="WIPING-CLOTH"} 3` + "\n",
+			`productmapper_clean_duration_seconds_count 2` + "\n",
+		} {
+			assert.Contains(t, b.String(), sample)
+		}
+	})
+}
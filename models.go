@@ -0,0 +1,124 @@
+package productmapper
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+type DeviceModel struct {
+	ModelId string
+	Brand   string
+	Series  string
+	Aliases []string
+}
+
+// ModelCatalog holds the known device models and their aliases.
+type ModelCatalog struct {
+	models  map[string]DeviceModel
+	aliases map[string]string // normalized model id or alias -> model id
+}
+
+func NewModelCatalog() *ModelCatalog {
+	return &ModelCatalog{
+		models:  map[string]DeviceModel{},
+		aliases: map[string]string{},
+	}
+}
+
+// Add registers model and its aliases, a model or alias registered earlier is overwritten.
+func (c *ModelCatalog) Add(model DeviceModel) {
+	c.models[model.ModelId] = model
+	c.aliases[normalizeModelId(model.ModelId)] = model.ModelId
+	for _, alias := range model.Aliases {
+		c.aliases[normalizeModelId(alias)] = model.ModelId
+	}
+}
+
+// Resolve returns the model for a model id or an alias, ignoring case and surrounding spaces.
+func (c *ModelCatalog) Resolve(modelId string) (DeviceModel, bool) {
+	canonical, ok := c.aliases[normalizeModelId(modelId)]
+	if !ok {
+		return DeviceModel{}, false
+	}
+	return c.models[canonical], true
+}
+
+// Models returns the registered models sorted by ModelId.
+func (c *ModelCatalog) Models() []DeviceModel {
+	models := make([]DeviceModel, 0, len(c.models))
+	for _, model := range c.models {
+		models = append(models, model)
+	}
+	slices.SortFunc(models, func(a, b DeviceModel) int {
+		return strings.Compare(a.ModelId, b.ModelId)
+	})
+	return models
+}
+
+func normalizeModelId(modelId string) string {
+	return strings.ToUpper(strings.TrimSpace(modelId))
+}
+
+var ErrInvalidModelCatalog = errors.New("invalid model catalog")
+
+var modelCatalogHeader = []string{"model_id", "brand", "series", "aliases"}
+
+// LoadModelCatalog reads a CSV catalog with the header
+//
+//	model_id,brand,series,aliases
+//
+// where aliases are separated by '|', e.g.
+//
+//	IPHONE16PROMAX,APPLE,IPHONE16,IPHONE16PM|IP16PROMAX
+func LoadModelCatalog(r io.Reader) (*ModelCatalog, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidModelCatalog, err)
+	}
+	if len(records) == 0 || !slices.Equal(records[0], modelCatalogHeader) {
+		return nil, fmt.Errorf("%w: missing header %s", ErrInvalidModelCatalog, strings.Join(modelCatalogHeader, ","))
+	}
+
+	catalog := NewModelCatalog()
+	for i, record := range records[1:] {
+		if record[0] == "" {
+			return nil, fmt.Errorf("%w: line %d: empty model id", ErrInvalidModelCatalog, i+2)
+		}
+
+		var aliases []string
+		for _, alias := range strings.Split(record[3], "|") {
+			if alias = strings.TrimSpace(alias); alias != "" {
+				aliases = append(aliases, alias)
+			}
+		}
+
+		catalog.Add(DeviceModel{
+			ModelId: record[0],
+			Brand:   record[1],
+			Series:  record[2],
+			Aliases: aliases,
+		})
+	}
+
+	return catalog, nil
+}
+
+// LoadModelCatalogFile reads a CSV catalog from path, see LoadModelCatalog.
+func LoadModelCatalogFile(path string) (*ModelCatalog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadModelCatalog(f)
+}
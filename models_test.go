@@ -0,0 +1,189 @@
+package productmapper_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+const modelCatalogCSV = `model_id,brand,series,aliases
+IPHONE16PROMAX,APPLE,IPHONE16,IPHONE16PM|IP16PROMAX
+OPPOA3,OPPO,A,
+OPPOA3-B,OPPO,A,OPPOA3B
+`
+
+func TestLoadModelCatalog(t *testing.T) {
+	t.Run("from reader", func(t *testing.T) {
+		catalog, err := productmapper.LoadModelCatalog(strings.NewReader(modelCatalogCSV))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []productmapper.DeviceModel{
+			{
+				ModelId: "IPHONE16PROMAX",
+				Brand:   "APPLE",
+				Series:  "IPHONE16",
+				Aliases: []string{"IPHONE16PM", "IP16PROMAX"},
+			},
+			{
+				ModelId: "OPPOA3",
+				Brand:   "OPPO",
+				Series:  "A",
+			},
+			{
+				ModelId: "OPPOA3-B",
+				Brand:   "OPPO",
+				Series:  "A",
+				Aliases: []string{"OPPOA3B"},
+			},
+		}, catalog.Models())
+	})
+
+	t.Run("from file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "models.csv")
+		assert.NoError(t, os.WriteFile(path, []byte(modelCatalogCSV), 0o644))
+
+		catalog, err := productmapper.LoadModelCatalogFile(path)
+
+		assert.NoError(t, err)
+		model, ok := catalog.Resolve("ip16promax")
+		assert.True(t, ok)
+		assert.Equal(t, "IPHONE16PROMAX", model.ModelId)
+	})
+
+	t.Run("wrong number of columns", func(t *testing.T) {
+		_, err := productmapper.LoadModelCatalog(strings.NewReader("model_id,brand,series,aliases\nOPPOA3,OPPO\n"))
+
+		assert.ErrorIs(t, err, productmapper.ErrInvalidModelCatalog)
+	})
+
+	t.Run("empty model id", func(t *testing.T) {
+		_, err := productmapper.LoadModelCatalog(strings.NewReader("model_id,brand,series,aliases\n,OPPO,A,\n"))
+
+		assert.ErrorIs(t, err, productmapper.ErrInvalidModelCatalog)
+	})
+
+	t.Run("missing header", func(t *testing.T) {
+		_, err := productmapper.LoadModelCatalog(strings.NewReader("IPHONE16PROMAX,APPLE,IPHONE16,IPHONE16PM\nOPPOA3,OPPO,A,\n"))
+
+		assert.ErrorIs(t, err, productmapper.ErrInvalidModelCatalog)
+	})
+}
+
+func TestExtractorModels(t *testing.T) {
+	catalog, err := productmapper.LoadModelCatalog(strings.NewReader(modelCatalogCSV))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name              string
+		mode              productmapper.Mode
+		platformProductId string
+		expected          *productmapper.ParseResult
+		err               error
+	}{
+		{
+			name:              "aliases resolve to the same model",
+			platformProductId: "FG0A-CLEAR-IPHONE16PM/FG0A-CLEAR-IP16PROMAX/FG0A-CLEAR-IPHONE16PROMAX",
+			expected: &productmapper.ParseResult{
+				Products: []productmapper.ProductParts{
+					{FilmTypeId: "FG0A", TextureId: "CLEAR", ModelId: "IPHONE16PROMAX", Qty: 1, Brand: "APPLE", Series: "IPHONE16"},
+					{FilmTypeId: "FG0A", TextureId: "CLEAR", ModelId: "IPHONE16PROMAX", Qty: 1, Brand: "APPLE", Series: "IPHONE16"},
+					{FilmTypeId: "FG0A", TextureId: "CLEAR", ModelId: "IPHONE16PROMAX", Qty: 1, Brand: "APPLE", Series: "IPHONE16"},
+				},
+				TotalQty: 3,
+			},
+		},
+		{
+			name:              "unknown model is a warning in lenient mode",
+			platformProductId: "FG0A-CLEAR-OPPOA3/FG0A-CLEAR-NOKIA3310",
+			expected: &productmapper.ParseResult{
+				Products: []productmapper.ProductParts{
+					{FilmTypeId: "FG0A", TextureId: "CLEAR", ModelId: "OPPOA3", Qty: 1, Brand: "OPPO", Series: "A"},
+					{FilmTypeId: "FG0A", TextureId: "CLEAR", ModelId: "NOKIA3310", Qty: 1},
+				},
+				TotalQty: 2,
+				Diagnostics: []productmapper.Diagnostic{
+					{
+						Code:    productmapper.DiagnosticUnknownModel,
+						Message: "unknown model id 'NOKIA3310'",
+						Input:   "FG0A-CLEAR-OPPOA3/FG0A-CLEAR-NOKIA3310",
+						Index:   29,
+						Value:   "NOKIA3310",
+					},
+				},
+			},
+		},
+		{
+			name:              "unknown model is an error in strict mode",
+			mode:              productmapper.ModeStrict,
+			platformProductId: "FG0A-CLEAR-NOKIA3310*2",
+			err: &productmapper.ParseError{
+				Message: "unknown model id 'NOKIA3310'",
+				Input:   "FG0A-CLEAR-NOKIA3310*2",
+				Index:   11,
+				Code:    productmapper.DiagnosticUnknownModel,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			extractor := productmapper.NewExtractor()
+			extractor.Models = catalog
+			extractor.Mode = test.mode
+
+			result, err := extractor.Parse(test.platformProductId)
+
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+
+	t.Run("model id that ends like a quantity", func(t *testing.T) {
+		catalog := productmapper.NewModelCatalog()
+		catalog.Add(productmapper.DeviceModel{ModelId: "VIVOX200", Brand: "VIVO", Series: "X", Aliases: []string{"VIVO X200"}})
+		extractor := productmapper.NewExtractor()
+		extractor.Models = catalog
+
+		products, totalQty, err := extractor.Extract("FG0A-CLEAR-vivo x200/FG0A-MATTE-VIVOX200 x2")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []productmapper.ProductParts{
+			{FilmTypeId: "FG0A", TextureId: "CLEAR", ModelId: "VIVOX200", Qty: 1, Brand: "VIVO", Series: "X"},
+			{FilmTypeId: "FG0A", TextureId: "MATTE", ModelId: "VIVOX200", Qty: 2, Brand: "VIVO", Series: "X"},
+		}, products)
+		assert.Equal(t, 3, totalQty)
+	})
+
+	t.Run("brand and series are kept on the cleaned line", func(t *testing.T) {
+		extractor := productmapper.NewExtractor()
+		extractor.Models = catalog
+
+		products, totalQty, err := extractor.Extract("FG0A-CLEAR-IP16PROMAX")
+		assert.NoError(t, err)
+
+		orders, err := productmapper.DiffusePrice(products, totalQty, productmapper.LineItemDetail{
+			Qty:        1,
+			UnitPrice:  50,
+			TotalPrice: 50,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []productmapper.CleanedOrder{
+			{
+				ProductId:  "FG0A-CLEAR-IPHONE16PROMAX",
+				MaterialId: "FG0A-CLEAR",
+				ModelId:    "IPHONE16PROMAX",
+				TextureId:  "CLEAR",
+				Brand:      "APPLE",
+				Series:     "IPHONE16",
+				Qty:        1,
+				UnitPrice:  50,
+				TotalPrice: 50,
+			},
+		}, orders)
+	})
+}
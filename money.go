@@ -0,0 +1,56 @@
+package productmapper
+
+import "math"
+
+// roundMoney rounds amount to 2 decimals, half away from zero.
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// toSatang converts amount to an integer number of satang (1/100 baht).
+func toSatang(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func fromSatang(amount int64) float64 {
+	return float64(amount) / 100
+}
+
+// allocate splits total by weights. Every share but the one with the largest
+// weight is rounded to 2 decimals, the largest takes what is left so the
+// shares always add up to total. Zero weights share total equally.
+func allocate(total float64, weights []float64) []float64 {
+	shares := make([]float64, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+
+	var sum float64
+	largest := 0
+	for i, weight := range weights {
+		sum += weight
+		if weight > weights[largest] {
+			largest = i
+		}
+	}
+
+	var allocated float64
+	for i, weight := range weights {
+		if i == largest {
+			continue
+		}
+		if sum == 0 {
+			shares[i] = roundMoney(total / float64(len(weights)))
+		} else {
+			shares[i] = roundMoney(total * weight / sum)
+		}
+		allocated += shares[i]
+	}
+	shares[largest] = total - allocated
+	if roundMoney(total) == total {
+		// drop the float noise of the subtraction, the exact remainder has 2 decimals too
+		shares[largest] = roundMoney(shares[largest])
+	}
+
+	return shares
+}
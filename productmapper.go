@@ -8,6 +8,11 @@ type InputOrder struct {
 	Qty               int
 	UnitPrice         float64
 	TotalPrice        float64
+	// Currency is the ISO 4217 code of the prices, e.g. THB, MYR or SGD.
+	Currency string
+	// FreeProducts are the ProductIds or MaterialIds of the bundle members
+	// given away for free on this line, see LineItemDetail.
+	FreeProducts []string
 }
 
 type CleanedOrder struct {
@@ -16,31 +21,68 @@ type CleanedOrder struct {
 	ProductId  string
 	MaterialId string
 	ModelId    string
-	Qty        int
-	UnitPrice  float64
-	TotalPrice float64
+	Brand      string
+	Series     string
+	// FilmMaterial and FilmThickness describe the film type, in millimetres for the thickness.
+	FilmMaterial  string
+	FilmThickness float64
+	Qty           int
+	UnitPrice     float64
+	TotalPrice    float64
+	// Currency is the currency of the prices, Discount, Fees and tax amounts, the one of the InputOrder.
+	Currency string
+	// ReportingCurrency, ExchangeRate, ReportingUnitPrice and ReportingTotalPrice
+	// are the prices converted to the reporting currency, see ConvertCurrency.
+	ReportingCurrency   string
+	ExchangeRate        float64
+	ReportingUnitPrice  float64
+	ReportingTotalPrice float64
+	// Discount is the share of the order level discounts, see ProrateDiscounts.
+	Discount float64
+	// Fees are the shares of the order level fees by fee type, see AllocateFees.
+	Fees map[string]float64
+	// NetAmount, TaxAmount and GrossAmount are the VAT breakdown, see ApplyTax.
+	NetAmount   float64
+	TaxAmount   float64
+	GrossAmount float64
+	// LineType is empty for lines that are both sold and picked, see ExpandBOM.
+	LineType LineType
+	// SourceNos are the InputOrder.No of the marketplace lines the product came from.
+	SourceNos []int
+	// Trace explains the price of the line in explain mode, see CleanOrderExplained.
+	Trace *PriceTrace
 }
 
+// IsSellable reports whether the line is sold to the customer.
+func (o *CleanedOrder) IsSellable() bool {
+	return o.LineType != LineComponent
+}
+
+// NetTotalPrice is TotalPrice after Discount.
+func (o *CleanedOrder) NetTotalPrice() float64 {
+	return o.TotalPrice - o.Discount
+}
+
+// IsPick reports whether the line is picked from stock.
+func (o *CleanedOrder) IsPick() bool {
+	return o.LineType != LineKit
+}
+
+// CleanOrder cleans orders with the default Mapper and complementaryItems.
 func CleanOrder(ctx context.Context, orders []InputOrder, complementaryItems []ComplementaryItem) ([]CleanedOrder, error) {
-	var cleanedOrders []CleanedOrder
-
-	for _, order := range orders {
-		productParts, totalQty, err := ExtractPlatformId(order.PlatformProductId)
-		if err != nil {
-			return nil, err
-		}
-
-		diffusedOrders, err := DiffusePrice(productParts, totalQty, LineItemDetail{
-			Qty:        order.Qty,
-			UnitPrice:  order.UnitPrice,
-			TotalPrice: order.TotalPrice,
-		})
-		if err != nil {
-			return nil, err
-		}
-
-		cleanedOrders = append(cleanedOrders, diffusedOrders...)
+	result, err := NewMapper(WithComplementaryItems(complementaryItems...)).Clean(ctx, orders)
+	if err != nil {
+		return nil, err
 	}
+	return result.Orders, nil
+}
 
-	return WithComplementary(cleanedOrders, complementaryItems), nil
+// CleanOrderExplained is CleanOrder with a PriceTrace on every diffused line.
+// ProrateDiscounts and AllocateFees add their allocations to the traces.
+func CleanOrderExplained(ctx context.Context, orders []InputOrder, complementaryItems []ComplementaryItem) ([]CleanedOrder, error) {
+	result, err := NewMapper(WithComplementaryItems(complementaryItems...), WithExplain()).Clean(ctx, orders)
+	if err != nil {
+		return nil, err
+	}
+	return result.Orders, nil
 }
@@ -50,6 +50,7 @@ func TestCleanOrder(t *testing.T) {
 					Qty:        2,
 					UnitPrice:  50,
 					TotalPrice: 100,
+					SourceNos:  []int{1},
 				},
 				{
 					No:        2,
@@ -85,6 +86,7 @@ func TestCleanOrder(t *testing.T) {
 					Qty:        2,
 					UnitPrice:  50,
 					TotalPrice: 100,
+					SourceNos:  []int{1},
 				},
 				{
 					No:        2,
@@ -120,6 +122,7 @@ func TestCleanOrder(t *testing.T) {
 					Qty:        3,
 					UnitPrice:  30,
 					TotalPrice: 90,
+					SourceNos:  []int{1},
 				},
 				{
 					No:        2,
@@ -156,6 +159,7 @@ func TestCleanOrder(t *testing.T) {
 					Qty:        1,
 					UnitPrice:  40,
 					TotalPrice: 40,
+					SourceNos:  []int{1},
 				},
 				{
 					No:         2,
@@ -166,6 +170,7 @@ func TestCleanOrder(t *testing.T) {
 					Qty:        1,
 					UnitPrice:  40,
 					TotalPrice: 40,
+					SourceNos:  []int{1},
 				},
 				{
 					No:        3,
@@ -202,6 +207,7 @@ func TestCleanOrder(t *testing.T) {
 					Qty:        1,
 					UnitPrice:  40,
 					TotalPrice: 40,
+					SourceNos:  []int{1},
 				},
 				{
 					No:         2,
@@ -212,6 +218,7 @@ func TestCleanOrder(t *testing.T) {
 					Qty:        1,
 					UnitPrice:  40,
 					TotalPrice: 40,
+					SourceNos:  []int{1},
 				},
 				{
 					No:         3,
@@ -222,6 +229,7 @@ func TestCleanOrder(t *testing.T) {
 					Qty:        1,
 					UnitPrice:  40,
 					TotalPrice: 40,
+					SourceNos:  []int{1},
 				},
 				{
 					No:        4,
@@ -263,6 +271,7 @@ func TestCleanOrder(t *testing.T) {
 					Qty:        2,
 					UnitPrice:  40,
 					TotalPrice: 80,
+					SourceNos:  []int{1},
 				},
 				{
 					No:         2,
@@ -273,6 +282,7 @@ func TestCleanOrder(t *testing.T) {
 					Qty:        1,
 					UnitPrice:  40,
 					TotalPrice: 40,
+					SourceNos:  []int{1},
 				},
 				{
 					No:        3,
@@ -321,6 +331,7 @@ func TestCleanOrder(t *testing.T) {
 					Qty:        2,
 					UnitPrice:  40,
 					TotalPrice: 80,
+					SourceNos:  []int{1},
 				},
 				{
 					No:         2,
@@ -331,6 +342,7 @@ func TestCleanOrder(t *testing.T) {
 					Qty:        2,
 					UnitPrice:  40,
 					TotalPrice: 80,
+					SourceNos:  []int{1},
 				},
 				{
 					No:         3,
@@ -341,6 +353,7 @@ func TestCleanOrder(t *testing.T) {
 					Qty:        1,
 					UnitPrice:  50,
 					TotalPrice: 50,
+					SourceNos:  []int{2},
 				},
 				{
 					No:        4,
@@ -364,6 +377,58 @@ func TestCleanOrder(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "grouped bundle with group quantity",
+			orders: []productmapper.InputOrder{
+				{
+					No:                1,
+					PlatformProductId: "(FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3)*3",
+					Qty:               1,
+					UnitPrice:         240,
+					TotalPrice:        240,
+				},
+			},
+			complementaryItems: complementaryItems,
+			expected: []productmapper.CleanedOrder{
+				{
+					No:         1,
+					ProductId:  "FG0A-CLEAR-OPPOA3",
+					MaterialId: "FG0A-CLEAR",
+					ModelId:    "OPPOA3",
+					TextureId:  "CLEAR",
+					Qty:        3,
+					UnitPrice:  40,
+					TotalPrice: 120,
+					SourceNos:  []int{1},
+				},
+				{
+					No:         2,
+					ProductId:  "FG0A-MATTE-OPPOA3",
+					MaterialId: "FG0A-MATTE",
+					ModelId:    "OPPOA3",
+					TextureId:  "MATTE",
+					Qty:        3,
+					UnitPrice:  40,
+					TotalPrice: 120,
+					SourceNos:  []int{1},
+				},
+				{
+					No:        3,
+					ProductId: "WIPING-CLOTH",
+					Qty:       6,
+				},
+				{
+					No:        4,
+					ProductId: "CLEAR-CLEANNER",
+					Qty:       3,
+				},
+				{
+					No:        5,
+					ProductId: "MATTE-CLEANNER",
+					Qty:       3,
+				},
+			},
+		},
 		// additional test case
 		{
 			name: "invalid platform product id: quantity symbol without number",
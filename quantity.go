@@ -0,0 +1,24 @@
+package productmapper
+
+import "math"
+
+// mulQty multiplies two quantities, ok is false when the result overflows int.
+func mulQty(a, b int) (int, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt) || (b == -1 && a == math.MinInt) {
+		return 0, false
+	}
+	return c, true
+}
+
+// addQty adds two quantities, ok is false when the result overflows int.
+func addQty(a, b int) (int, bool) {
+	c := a + b
+	if (b > 0 && c < a) || (b < 0 && c > a) {
+		return 0, false
+	}
+	return c, true
+}
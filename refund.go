@@ -0,0 +1,154 @@
+package productmapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// ReturnedItem is a product returned from an InputOrder.
+type ReturnedItem struct {
+	ProductId string
+	Qty       int
+}
+
+type RefundLine struct {
+	ProductId string
+	Qty       int
+	// Amount is the share of the InputOrder TotalPrice refunded for Qty, zero
+	// for complementary items.
+	Amount float64
+}
+
+type Refund struct {
+	No    int
+	Lines []RefundLine
+	// Complementary are the complementary items given with the returned
+	// products, to be taken back into stock.
+	Complementary []RefundLine
+	Amount        float64
+}
+
+var (
+	ErrNotInOrder     = errors.New("product not in order")
+	ErrRefundExceeded = errors.New("returned quantity exceeds ordered quantity")
+)
+
+// CalculateRefund reverses the diffusion of order for the returned products
+// with the default Mapper, see Mapper.Refund.
+func CalculateRefund(order InputOrder, returned []ReturnedItem, complementaryItems []ComplementaryItem) (*Refund, error) {
+	return NewMapper(WithComplementaryItems(complementaryItems...)).Refund(context.Background(), order, returned)
+}
+
+// Refund reverses the diffusion of order for the returned products. The line
+// price is split over the products the way the parser and allocator of the
+// Mapper do and rounded to 2 decimals so that returning every product refunds
+// exactly the order TotalPrice. A product is refunded in proportion to its
+// returned Qty. The complementary items of the Mapper given with the returned
+// products are listed in Complementary.
+func (m *Mapper) Refund(ctx context.Context, order InputOrder, returned []ReturnedItem) (*Refund, error) {
+	extractorLogger, diffuserLogger := m.extractor.Logger, m.diffuser.Logger
+	if m.logger != nil {
+		extractorLogger = m.logger.With(slog.Int("order_no", order.No), slog.String("platform_product_id", order.PlatformProductId))
+		diffuserLogger = extractorLogger
+	}
+
+	parsed, err := m.extractor.parse(ctx, extractorLogger, order.PlatformProductId)
+	if err != nil {
+		return nil, err
+	}
+	result, err := m.diffuser.diffuse(ctx, diffuserLogger, parsed.Products, parsed.TotalQty, LineItemDetail{
+		Qty:          order.Qty,
+		UnitPrice:    order.UnitPrice,
+		TotalPrice:   order.TotalPrice,
+		FreeProducts: order.FreeProducts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	diffused := result.Orders
+
+	// a product can be in a bundle more than once, e.g. A/B/A
+	products := map[string]*CleanedOrder{}
+	var ids []string
+	for _, line := range diffused {
+		if product, ok := products[line.ProductId]; ok {
+			product.Qty += line.Qty
+			product.TotalPrice += line.TotalPrice
+			continue
+		}
+		line := line
+		products[line.ProductId] = &line
+		ids = append(ids, line.ProductId)
+	}
+
+	weights := make([]float64, len(ids))
+	for i, id := range ids {
+		weights[i] = products[id].TotalPrice
+	}
+	for i, amount := range allocate(order.TotalPrice, weights) {
+		products[ids[i]].TotalPrice = amount
+	}
+
+	refund := &Refund{No: order.No}
+	returnedQty := map[string]int{}
+	var returnedLines []CleanedOrder
+
+	for _, item := range returned {
+		product, ok := products[item.ProductId]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNotInOrder, item.ProductId)
+		}
+		if item.Qty <= 0 {
+			return nil, fmt.Errorf("%w: %d of %s", ErrInvalidQty, item.Qty, item.ProductId)
+		}
+
+		previousQty := returnedQty[item.ProductId]
+		qty, ok := addQty(previousQty, item.Qty)
+		if !ok || qty > product.Qty {
+			return nil, fmt.Errorf("%w: %d of %s, ordered %d", ErrRefundExceeded, qty, item.ProductId, product.Qty)
+		}
+		returnedQty[item.ProductId] = qty
+
+		// refunded up to qty less refunded up to previousQty, so returning a
+		// product in several items refunds the same as returning it at once
+		amount := fromSatang(refundedSatang(product, qty) - refundedSatang(product, previousQty))
+		refund.Lines = append(refund.Lines, RefundLine{
+			ProductId: item.ProductId,
+			Qty:       item.Qty,
+			Amount:    amount,
+		})
+
+		returnedLine := *product
+		returnedLine.Qty = item.Qty
+		returnedLines = append(returnedLines, returnedLine)
+	}
+
+	var refunded int64
+	for _, line := range refund.Lines {
+		refunded += toSatang(line.Amount)
+	}
+	refund.Amount = fromSatang(refunded)
+
+	withComplementary, err := withComplementary(ctx, m.logger, noopMetrics{}, returnedLines, m.complementaryItems, m.strategies)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range withComplementary[len(returnedLines):] {
+		refund.Complementary = append(refund.Complementary, RefundLine{
+			ProductId: line.ProductId,
+			Qty:       line.Qty,
+		})
+	}
+
+	return refund, nil
+}
+
+// refundedSatang is the refund in satang for qty of product.
+func refundedSatang(product *CleanedOrder, qty int) int64 {
+	if qty == product.Qty {
+		return toSatang(product.TotalPrice)
+	}
+	return toSatang(product.TotalPrice * float64(qty) / float64(product.Qty))
+}
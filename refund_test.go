@@ -0,0 +1,206 @@
+package productmapper_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateRefund(t *testing.T) {
+	order := productmapper.InputOrder{
+		No:                1,
+		PlatformProductId: "FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3*2/FG0A-PRIVACY-OPPOA3",
+		Qty:               1,
+		UnitPrice:         100,
+		TotalPrice:        100,
+	}
+	complementaryItems := []productmapper.ComplementaryItem{
+		{
+			ProductId: "WIPING-CLOTH",
+			PerQty:    1,
+		},
+		{
+			ProductId: "CLEANNER",
+			PerQty:    1,
+			Type:      "SUFFIX_TEXTURE",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		returned []productmapper.ReturnedItem
+		expected *productmapper.Refund
+		err      error
+	}{
+		{
+			name: "one film out of a bundle",
+			returned: []productmapper.ReturnedItem{
+				{ProductId: "FG0A-MATTE-OPPOA3", Qty: 1},
+			},
+			expected: &productmapper.Refund{
+				No: 1,
+				Lines: []productmapper.RefundLine{
+					{ProductId: "FG0A-MATTE-OPPOA3", Qty: 1, Amount: 25},
+				},
+				Complementary: []productmapper.RefundLine{
+					{ProductId: "WIPING-CLOTH", Qty: 1},
+					{ProductId: "MATTE-CLEANNER", Qty: 1},
+				},
+				Amount: 25,
+			},
+		},
+		{
+			name: "whole order refunds the total price",
+			returned: []productmapper.ReturnedItem{
+				{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1},
+				{ProductId: "FG0A-MATTE-OPPOA3", Qty: 1},
+				{ProductId: "FG0A-MATTE-OPPOA3", Qty: 1},
+				{ProductId: "FG0A-PRIVACY-OPPOA3", Qty: 1},
+			},
+			expected: &productmapper.Refund{
+				No: 1,
+				Lines: []productmapper.RefundLine{
+					{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1, Amount: 25},
+					{ProductId: "FG0A-MATTE-OPPOA3", Qty: 1, Amount: 25},
+					{ProductId: "FG0A-MATTE-OPPOA3", Qty: 1, Amount: 25},
+					{ProductId: "FG0A-PRIVACY-OPPOA3", Qty: 1, Amount: 25},
+				},
+				Complementary: []productmapper.RefundLine{
+					{ProductId: "WIPING-CLOTH", Qty: 4},
+					{ProductId: "CLEAR-CLEANNER", Qty: 1},
+					{ProductId: "MATTE-CLEANNER", Qty: 2},
+					{ProductId: "PRIVACY-CLEANNER", Qty: 1},
+				},
+				Amount: 100,
+			},
+		},
+		{
+			name: "product not in the order",
+			returned: []productmapper.ReturnedItem{
+				{ProductId: "FG0A-CLEAR-IPHONE16PROMAX", Qty: 1},
+			},
+			err: productmapper.ErrNotInOrder,
+		},
+		{
+			name: "more than ordered",
+			returned: []productmapper.ReturnedItem{
+				{ProductId: "FG0A-MATTE-OPPOA3", Qty: 2},
+				{ProductId: "FG0A-MATTE-OPPOA3", Qty: 1},
+			},
+			err: productmapper.ErrRefundExceeded,
+		},
+		{
+			name: "zero quantity",
+			returned: []productmapper.ReturnedItem{
+				{ProductId: "FG0A-MATTE-OPPOA3"},
+			},
+			err: productmapper.ErrInvalidQty,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			refund, err := productmapper.CalculateRefund(order, test.returned, complementaryItems)
+
+			assert.ErrorIs(t, err, test.err)
+			assert.Equal(t, test.expected, refund)
+		})
+	}
+
+	t.Run("rounded refunds of one return add up to the total price", func(t *testing.T) {
+		order := productmapper.InputOrder{
+			No:                2,
+			PlatformProductId: "FG0A-CLEAR-OPPOA3*3",
+			Qty:               1,
+			UnitPrice:         100,
+			TotalPrice:        100,
+		}
+
+		for range 3 {
+			refund, err := productmapper.CalculateRefund(order, []productmapper.ReturnedItem{
+				{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1},
+			}, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, 33.33, refund.Amount)
+		}
+
+		refund, err := productmapper.CalculateRefund(order, []productmapper.ReturnedItem{
+			{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1},
+			{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 2},
+		}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []productmapper.RefundLine{
+			{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1, Amount: 33.33},
+			{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 2, Amount: 66.67},
+		}, refund.Lines)
+		assert.Equal(t, 100.0, refund.Amount)
+	})
+}
+
+func TestMapperRefund(t *testing.T) {
+	t.Run("floors of the allocator", func(t *testing.T) {
+		mapper := productmapper.NewMapper(productmapper.WithAllocator(&productmapper.Diffuser{
+			Floors: map[string]float64{"FG0A-CLEAR": 80},
+		}))
+
+		refund, err := mapper.Refund(context.Background(), productmapper.InputOrder{
+			No:                1,
+			PlatformProductId: "FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3",
+			Qty:               1,
+			UnitPrice:         100,
+			TotalPrice:        100,
+		}, []productmapper.ReturnedItem{{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 80.0, refund.Amount)
+	})
+
+	t.Run("free products of the line", func(t *testing.T) {
+		refund, err := productmapper.NewMapper().Refund(context.Background(), productmapper.InputOrder{
+			No:                1,
+			PlatformProductId: "FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3",
+			Qty:               1,
+			UnitPrice:         100,
+			TotalPrice:        100,
+			FreeProducts:      []string{"FG0A-MATTE"},
+		}, []productmapper.ReturnedItem{
+			{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1},
+			{ProductId: "FG0A-MATTE-OPPOA3", Qty: 1},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []productmapper.RefundLine{
+			{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1, Amount: 100},
+			{ProductId: "FG0A-MATTE-OPPOA3", Qty: 1, Amount: 0},
+		}, refund.Lines)
+	})
+
+	t.Run("model aliases of the parser", func(t *testing.T) {
+		catalog, err := productmapper.LoadModelCatalog(strings.NewReader(modelCatalogCSV))
+		assert.NoError(t, err)
+		parser := productmapper.NewExtractor()
+		parser.Models = catalog
+
+		refund, err := productmapper.NewMapper(
+			productmapper.WithParser(parser),
+			productmapper.WithComplementaryItems(productmapper.ComplementaryItem{ProductId: "WIPING-CLOTH", PerQty: 1}),
+		).Refund(context.Background(), productmapper.InputOrder{
+			No:                1,
+			PlatformProductId: "FG0A-CLEAR-IPHONE16PM*2",
+			Qty:               1,
+			UnitPrice:         100,
+			TotalPrice:        100,
+		}, []productmapper.ReturnedItem{{ProductId: "FG0A-CLEAR-IPHONE16PROMAX", Qty: 1}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &productmapper.Refund{
+			No:            1,
+			Lines:         []productmapper.RefundLine{{ProductId: "FG0A-CLEAR-IPHONE16PROMAX", Qty: 1, Amount: 50}},
+			Complementary: []productmapper.RefundLine{{ProductId: "WIPING-CLOTH", Qty: 1}},
+			Amount:        50,
+		}, refund)
+	})
+}
@@ -0,0 +1,264 @@
+package productmapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// Names of the built-in stages of Mapper.Clean, in the order they run.
+const (
+	StageExtract       = "extract"
+	StageDiffuse       = "diffuse"
+	StageProrate       = "prorate"
+	StageComplementary = "complementary"
+	StageCatalog       = "catalog"
+)
+
+// Stage is a step of Mapper.Clean. The stages run one after another on the
+// same OrderContext, the first error stops Clean.
+type Stage interface {
+	Name() string
+	Process(ctx context.Context, oc *OrderContext) error
+}
+
+type stageFunc struct {
+	name    string
+	process func(ctx context.Context, oc *OrderContext) error
+}
+
+func (s stageFunc) Name() string { return s.name }
+
+func (s stageFunc) Process(ctx context.Context, oc *OrderContext) error {
+	return s.process(ctx, oc)
+}
+
+// NewStage returns a Stage named name that runs process.
+func NewStage(name string, process func(ctx context.Context, oc *OrderContext) error) Stage {
+	return stageFunc{name: name, process: process}
+}
+
+// OrderContext is the state of a Mapper.Clean call shared by its stages.
+// Stages before StageDiffuse work on Lines, the diffuse stage sets Orders
+// from them and the stages after it work on Orders.
+type OrderContext struct {
+	Lines         []*OrderLine
+	Orders        []CleanedOrder
+	Diagnostics   []Diagnostic
+	CatalogIssues []CatalogIssue
+	// Header is the order level amounts given to Mapper.CleanWithHeader, nil
+	// for Mapper.Clean.
+	Header *OrderHeader
+
+	// Logger, Metrics and Tracer are the ones of the Mapper, Logger may be nil.
+	Logger  *slog.Logger
+	Metrics Metrics
+	Tracer  Tracer
+}
+
+// OrderLine is an InputOrder going through the stages.
+type OrderLine struct {
+	Input InputOrder
+	// Products and TotalQty are set by StageExtract.
+	Products []ProductParts
+	TotalQty int
+	// Orders are the diffused lines, set by StageDiffuse.
+	Orders []CleanedOrder
+}
+
+// logger returns the logger of a stage for line, the Mapper logger with the
+// order attributes or the fallback of the stage.
+func (oc *OrderContext) logger(line *OrderLine, fallback *slog.Logger) *slog.Logger {
+	if oc.Logger == nil {
+		return fallback
+	}
+	return oc.Logger.With(slog.Int("order_no", line.Input.No), slog.String("platform_product_id", line.Input.PlatformProductId))
+}
+
+// startOrder starts the span of line within a stage.
+func (oc *OrderContext) startOrder(ctx context.Context, line *OrderLine) (context.Context, Span) {
+	return oc.Tracer.Start(ctx, SpanOrder, slog.Int("order_no", line.Input.No), slog.String("platform_product_id", line.Input.PlatformProductId))
+}
+
+var ErrUnknownStage = errors.New("unknown stage")
+
+// stageInsertion is a WithStageBefore or WithStageAfter option.
+type stageInsertion struct {
+	name   string
+	after  bool
+	stages []Stage
+}
+
+// WithStageBefore runs stages before the stage named name, a built-in stage
+// or one added by an earlier option.
+func WithStageBefore(name string, stages ...Stage) Option {
+	return func(c *mapperConfig) {
+		c.insertions = append(c.insertions, stageInsertion{name: name, stages: stages})
+	}
+}
+
+// WithStageAfter runs stages after the stage named name, a built-in stage
+// or one added by an earlier option.
+func WithStageAfter(name string, stages ...Stage) Option {
+	return func(c *mapperConfig) {
+		c.insertions = append(c.insertions, stageInsertion{name: name, after: true, stages: stages})
+	}
+}
+
+// insertStages returns stages with the insertions applied in order.
+func insertStages(stages []Stage, insertions []stageInsertion) ([]Stage, error) {
+	for _, insertion := range insertions {
+		i := slices.IndexFunc(stages, func(stage Stage) bool { return stage.Name() == insertion.name })
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownStage, insertion.name)
+		}
+		if insertion.after {
+			i++
+		}
+		stages = slices.Insert(slices.Clip(stages), i, insertion.stages...)
+	}
+	return stages, nil
+}
+
+// extractStage parses the platform product id of every line.
+type extractStage struct {
+	extractor *Extractor
+}
+
+func (s *extractStage) Name() string { return StageExtract }
+
+func (s *extractStage) Process(ctx context.Context, oc *OrderContext) error {
+	for _, line := range oc.Lines {
+		orderCtx, span := oc.startOrder(ctx, line)
+		parsed, err := s.extractor.parse(orderCtx, oc.logger(line, s.extractor.Logger), line.Input.PlatformProductId)
+		if err != nil {
+			endSpan(span, err)
+			oc.Metrics.ParseError(parseErrorCode(err))
+			return err
+		}
+		span.SetAttributes(slog.Int("products", len(parsed.Products)), slog.Int("diagnostics", len(parsed.Diagnostics)))
+		span.End()
+
+		for _, diagnostic := range parsed.Diagnostics {
+			oc.Metrics.ParseError(string(diagnostic.Code))
+		}
+		oc.Diagnostics = append(oc.Diagnostics, parsed.Diagnostics...)
+		line.Products = parsed.Products
+		line.TotalQty = parsed.TotalQty
+	}
+	return nil
+}
+
+// diffuseStage diffuses the price of every line over its products.
+type diffuseStage struct {
+	diffuser *Diffuser
+}
+
+func (s *diffuseStage) Name() string { return StageDiffuse }
+
+func (s *diffuseStage) Process(ctx context.Context, oc *OrderContext) error {
+	oc.Orders = nil
+	for _, line := range oc.Lines {
+		orderCtx, span := oc.startOrder(ctx, line)
+		diffused, err := s.diffuser.diffuse(orderCtx, oc.logger(line, s.diffuser.Logger), line.Products, line.TotalQty, LineItemDetail{
+			Qty:          line.Input.Qty,
+			UnitPrice:    line.Input.UnitPrice,
+			TotalPrice:   line.Input.TotalPrice,
+			FreeProducts: line.Input.FreeProducts,
+		})
+		if err != nil {
+			endSpan(span, err)
+			return err
+		}
+		span.SetAttributes(slog.Int("lines", len(diffused.Orders)))
+		span.End()
+
+		for _, diagnostic := range diffused.Diagnostics {
+			diagnostic.Input = line.Input.PlatformProductId
+			oc.Diagnostics = append(oc.Diagnostics, diagnostic)
+		}
+		for i := range diffused.Orders {
+			diffused.Orders[i].SourceNos = []int{line.Input.No}
+			diffused.Orders[i].Currency = line.Input.Currency
+		}
+		line.Orders = diffused.Orders
+		oc.Orders = append(oc.Orders, diffused.Orders...)
+		oc.Metrics.OrdersProcessed(1)
+	}
+	return nil
+}
+
+// prorateStage spreads the discounts and fees of the order header over the
+// orders, when there is a header.
+type prorateStage struct {
+	discountBasis ProrationBasis
+	feeBasis      ProrationBasis
+	feeWeight     WeightFunc
+}
+
+func (s *prorateStage) Name() string { return StageProrate }
+
+func (s *prorateStage) Process(ctx context.Context, oc *OrderContext) error {
+	if oc.Header == nil {
+		return nil
+	}
+
+	orders, err := ProrateDiscounts(oc.Orders, *oc.Header, s.discountBasis)
+	if err != nil {
+		return err
+	}
+	orders, err = AllocateFees(orders, *oc.Header, s.feeBasis, s.feeWeight)
+	if err != nil {
+		return err
+	}
+	oc.Orders = orders
+	return nil
+}
+
+// complementaryStage numbers the orders and adds the complementary items.
+type complementaryStage struct {
+	items      []ComplementaryItem
+	strategies map[string]ComplementaryStrategy
+}
+
+func (s *complementaryStage) Name() string { return StageComplementary }
+
+func (s *complementaryStage) Process(ctx context.Context, oc *OrderContext) error {
+	orders, err := withComplementary(ctx, oc.Logger, oc.Metrics, oc.Orders, s.items, s.strategies)
+	if err != nil {
+		return err
+	}
+	oc.Orders = orders
+	return nil
+}
+
+// catalogStage checks the orders against the catalog, when there is one.
+type catalogStage struct {
+	catalog Catalog
+	mode    Mode
+}
+
+func (s *catalogStage) Name() string { return StageCatalog }
+
+func (s *catalogStage) Process(ctx context.Context, oc *OrderContext) error {
+	if s.catalog == nil {
+		return nil
+	}
+
+	issues, err := CheckCatalog(ctx, s.catalog, oc.Orders, s.mode)
+	if err != nil {
+		return err
+	}
+	oc.CatalogIssues = issues
+	for _, issue := range issues {
+		logAttrs(ctx, oc.Logger, slog.LevelWarn, "not found in catalog",
+			slog.Int("line_no", issue.No),
+			slog.String("product_id", issue.ProductId),
+			slog.String("field", issue.Field),
+			slog.String("value", issue.Value),
+		)
+	}
+	return nil
+}
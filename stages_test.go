@@ -0,0 +1,140 @@
+package productmapper_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapperStages(t *testing.T) {
+	blacklist := productmapper.NewStage("blacklist", func(ctx context.Context, oc *productmapper.OrderContext) error {
+		oc.Lines = slices.DeleteFunc(oc.Lines, func(line *productmapper.OrderLine) bool {
+			return line.Input.PlatformProductId == "TEST-SKU"
+		})
+		return nil
+	})
+	remap := productmapper.NewStage("remap", func(ctx context.Context, oc *productmapper.OrderContext) error {
+		for _, line := range oc.Lines {
+			for i := range line.Products {
+				if line.Products[i].ModelId == "OPPOA3S" {
+					line.Products[i].ModelId = "OPPOA3"
+				}
+			}
+		}
+		return nil
+	})
+	giftWrap := productmapper.NewStage("gift-wrap", func(ctx context.Context, oc *productmapper.OrderContext) error {
+		oc.Orders = append(oc.Orders, productmapper.CleanedOrder{
+			No:        len(oc.Orders) + 1,
+			ProductId: "GIFT-WRAP",
+			Qty:       1,
+		})
+		return nil
+	})
+
+	orders := []productmapper.InputOrder{
+		{No: 1, PlatformProductId: "TEST-SKU", Qty: 1},
+		{No: 2, PlatformProductId: "FG0A-CLEAR-OPPOA3S", Qty: 1, UnitPrice: 100, TotalPrice: 100},
+	}
+
+	t.Run("user stages before and after the built-in ones", func(t *testing.T) {
+		mapper := productmapper.NewMapper(
+			productmapper.WithComplementaryItems(productmapper.ComplementaryItem{ProductId: "WIPING-CLOTH", PerQty: 1}),
+			productmapper.WithStageBefore(productmapper.StageExtract, blacklist),
+			productmapper.WithStageAfter(productmapper.StageExtract, remap),
+			productmapper.WithStageAfter(productmapper.StageComplementary, giftWrap),
+		)
+
+		result, err := mapper.Clean(context.Background(), orders)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"blacklist", "extract", "remap", "diffuse", "prorate", "complementary", "gift-wrap", "catalog"}, mapper.Stages())
+		assert.Equal(t, []productmapper.CleanedOrder{
+			{
+				No:         1,
+				ProductId:  "FG0A-CLEAR-OPPOA3",
+				MaterialId: "FG0A-CLEAR",
+				ModelId:    "OPPOA3",
+				TextureId:  "CLEAR",
+				Qty:        1,
+				UnitPrice:  100,
+				TotalPrice: 100,
+				SourceNos:  []int{2},
+			},
+			{No: 2, ProductId: "WIPING-CLOTH", Qty: 1},
+			{No: 3, ProductId: "GIFT-WRAP", Qty: 1},
+		}, result.Orders)
+		assert.Equal(t, "TEST-SKU", orders[0].PlatformProductId, "input orders are not modified")
+	})
+
+	t.Run("relative to a user stage", func(t *testing.T) {
+		mapper := productmapper.NewMapper(
+			productmapper.WithStageBefore(productmapper.StageExtract, blacklist),
+			productmapper.WithStageBefore("blacklist", giftWrap),
+		)
+
+		assert.Equal(t, []string{"gift-wrap", "blacklist", "extract", "diffuse", "prorate", "complementary", "catalog"}, mapper.Stages())
+	})
+
+	t.Run("order header prorated before the complementary items", func(t *testing.T) {
+		mapper := productmapper.NewMapper(
+			productmapper.WithComplementaryItems(productmapper.ComplementaryItem{ProductId: "WIPING-CLOTH", PerQty: 1}),
+			productmapper.WithFeeBasis(productmapper.ProrateByQty, nil),
+		)
+
+		result, err := mapper.CleanWithHeader(context.Background(), productmapper.OrderHeader{
+			Discounts: []productmapper.OrderDiscount{{Type: productmapper.DiscountShopVoucher, Amount: 30}},
+			Fees:      []productmapper.OrderFee{{Type: productmapper.FeeShipping, Amount: 40}},
+		}, []productmapper.InputOrder{
+			{No: 1, PlatformProductId: "FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3", Qty: 1, UnitPrice: 100, TotalPrice: 100},
+			{No: 2, PlatformProductId: "FG0A-CLEAR-OPPOA3", Qty: 1, UnitPrice: 100, TotalPrice: 100},
+		})
+
+		assert.NoError(t, err)
+		var discounts []float64
+		var fees []float64
+		for _, order := range result.Orders {
+			discounts = append(discounts, order.Discount)
+			fees = append(fees, order.Fees[productmapper.FeeShipping])
+		}
+		assert.Equal(t, []float64{7.5, 7.5, 15, 0}, discounts)
+		assert.Equal(t, []float64{13.34, 13.33, 13.33, 0}, fees)
+		assert.Equal(t, "WIPING-CLOTH", result.Orders[3].ProductId)
+	})
+
+	t.Run("no header leaves the lines as diffused", func(t *testing.T) {
+		result, err := productmapper.NewMapper().Clean(context.Background(), orders[1:])
+
+		assert.NoError(t, err)
+		assert.Zero(t, result.Orders[0].Discount)
+		assert.Nil(t, result.Orders[0].Fees)
+	})
+
+	t.Run("unknown stage", func(t *testing.T) {
+		_, err := productmapper.NewMapper(productmapper.WithStageAfter("dedupe", giftWrap)).Clean(context.Background(), orders)
+
+		assert.ErrorIs(t, err, productmapper.ErrUnknownStage)
+	})
+
+	t.Run("stage error stops the pipeline", func(t *testing.T) {
+		errRejected := errors.New("rejected")
+		ran := false
+
+		_, err := productmapper.NewMapper(
+			productmapper.WithStageBefore(productmapper.StageExtract, productmapper.NewStage("reject", func(ctx context.Context, oc *productmapper.OrderContext) error {
+				return errRejected
+			})),
+			productmapper.WithStageAfter(productmapper.StageExtract, productmapper.NewStage("after", func(ctx context.Context, oc *productmapper.OrderContext) error {
+				ran = true
+				return nil
+			})),
+		).Clean(context.Background(), orders)
+
+		assert.ErrorIs(t, err, errRejected)
+		assert.False(t, ran)
+	})
+}
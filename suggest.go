@@ -0,0 +1,220 @@
+package productmapper
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	DefaultMaxSuggestions      = 3
+	DefaultMaxRelativeDistance = 0.4
+)
+
+// Suggester ranks catalog entries by how close they are to an unknown id.
+// The distance is an edit distance where swapping two neighbouring characters
+// costs 1 and a substitution with a neighbouring keyboard key or a look-alike
+// character (O/0, I/1) costs 0.5. Ids whose tokens are only reordered, e.g.
+// "16IPHONEPROMAX" and "IPHONE16PROMAX", are 0.5 apart.
+type Suggester struct {
+	// MaxSuggestions limits the number of suggestions, 0 means no limit.
+	MaxSuggestions int
+	// MaxRelativeDistance drops candidates whose distance divided by the
+	// length of the longer id is above it.
+	MaxRelativeDistance float64
+}
+
+func NewSuggester() *Suggester {
+	return &Suggester{
+		MaxSuggestions:      DefaultMaxSuggestions,
+		MaxRelativeDistance: DefaultMaxRelativeDistance,
+	}
+}
+
+type Suggestion struct {
+	Value    string
+	Distance float64
+}
+
+// Suggest returns the candidates closest to value, closest first.
+func (s *Suggester) Suggest(value string, candidates []string) []Suggestion {
+	var suggestions []Suggestion
+
+	for _, candidate := range candidates {
+		distance := idDistance(value, candidate)
+		if distance/float64(max(len(value), len(candidate), 1)) > s.MaxRelativeDistance {
+			continue
+		}
+		suggestions = append(suggestions, Suggestion{
+			Value:    candidate,
+			Distance: distance,
+		})
+	}
+
+	slices.SortStableFunc(suggestions, func(a, b Suggestion) int {
+		if c := cmp.Compare(a.Distance, b.Distance); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Value, b.Value)
+	})
+
+	if s.MaxSuggestions > 0 && len(suggestions) > s.MaxSuggestions {
+		suggestions = suggestions[:s.MaxSuggestions]
+	}
+
+	return suggestions
+}
+
+// suggestIds suggests the canonical ids of names, a map of id or alias -> id.
+func (s *Suggester) suggestIds(value string, names map[string]string) []string {
+	candidates := make([]string, 0, len(names))
+	for name := range names {
+		candidates = append(candidates, name)
+	}
+
+	unlimited := *s
+	unlimited.MaxSuggestions = 0
+
+	var ids []string
+	for _, suggestion := range unlimited.Suggest(value, candidates) {
+		id := names[suggestion.Value]
+		if slices.Contains(ids, id) {
+			continue
+		}
+		ids = append(ids, id)
+		if s.MaxSuggestions > 0 && len(ids) == s.MaxSuggestions {
+			break
+		}
+	}
+
+	return ids
+}
+
+func idDistance(a, b string) float64 {
+	a = strings.ToUpper(a)
+	b = strings.ToUpper(b)
+
+	distance := editDistance(a, b)
+
+	sortedA, sortedB := sortedTokens(a), sortedTokens(b)
+	if sortedA != a || sortedB != b {
+		distance = min(distance, editDistance(sortedA, sortedB)+0.5)
+	}
+
+	return distance
+}
+
+// editDistance is the optimal string alignment distance with keyboard aware substitutions.
+func editDistance(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+
+	d := make([][]float64, len(ra)+1)
+	for i := range d {
+		d[i] = make([]float64, len(rb)+1)
+		d[i][0] = float64(i)
+	}
+	for j := range d[0] {
+		d[0][j] = float64(j)
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			d[i][j] = min(
+				d[i-1][j]+1,
+				d[i][j-1]+1,
+				d[i-1][j-1]+substitutionCost(ra[i-1], rb[j-1]),
+			)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(ra)][len(rb)]
+}
+
+func substitutionCost(a, b rune) float64 {
+	if a == b {
+		return 0
+	}
+	if lookAlike[a] == b || lookAlike[b] == a || keyboardNeighbours(a, b) {
+		return 0.5
+	}
+	return 1
+}
+
+var lookAlike = map[rune]rune{
+	'O': '0',
+	'I': '1',
+	'S': '5',
+	'B': '8',
+}
+
+var keyboardRows = []string{
+	"1234567890",
+	"QWERTYUIOP",
+	"ASDFGHJKL",
+	"ZXCVBNM",
+}
+
+type keyPosition struct {
+	row, col int
+}
+
+var keyPositions = func() map[rune]keyPosition {
+	positions := map[rune]keyPosition{}
+	for row, keys := range keyboardRows {
+		for col, key := range keys {
+			positions[key] = keyPosition{row: row, col: col}
+		}
+	}
+	return positions
+}()
+
+// keyboardNeighbours reports whether a and b are next to each other on a QWERTY keyboard.
+func keyboardNeighbours(a, b rune) bool {
+	pa, okA := keyPositions[a]
+	pb, okB := keyPositions[b]
+	if !okA || !okB {
+		return false
+	}
+	if pa.row > pb.row {
+		pa, pb = pb, pa
+	}
+
+	switch pb.row - pa.row {
+	case 0:
+		return pb.col-pa.col == 1 || pa.col-pb.col == 1
+	case 1:
+		// every row is shifted half a key to the right of the row above
+		return pb.col == pa.col || pb.col == pa.col-1
+	}
+	return false
+}
+
+// sortedTokens splits id into runs of letters and runs of digits and joins them sorted.
+func sortedTokens(id string) string {
+	var tokens []string
+	var current []rune
+	for _, c := range id {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			if len(current) > 0 {
+				tokens = append(tokens, string(current))
+				current = current[:0]
+			}
+			continue
+		}
+		if len(current) > 0 && unicode.IsDigit(c) != unicode.IsDigit(current[len(current)-1]) {
+			tokens = append(tokens, string(current))
+			current = current[:0]
+		}
+		current = append(current, c)
+	}
+	if len(current) > 0 {
+		tokens = append(tokens, string(current))
+	}
+
+	slices.Sort(tokens)
+	return strings.Join(tokens, "")
+}
@@ -0,0 +1,102 @@
+package productmapper_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggester(t *testing.T) {
+	candidates := []string{"IPHONE16PROMAX", "IPHONE16PRO", "IPHONE15PROMAX", "OPPOA3", "OPPOA3-B"}
+
+	tests := []struct {
+		name     string
+		value    string
+		expected []productmapper.Suggestion
+	}{
+		{
+			name:  "keyboard typo is closer than a different model",
+			value: "IPHONE16PROMAZ",
+			expected: []productmapper.Suggestion{
+				{Value: "IPHONE16PROMAX", Distance: 0.5},
+				{Value: "IPHONE15PROMAX", Distance: 1},
+				{Value: "IPHONE16PRO", Distance: 3},
+			},
+		},
+		{
+			name:  "reordered tokens",
+			value: "16IPHONEPROMAX",
+			expected: []productmapper.Suggestion{
+				{Value: "IPHONE16PROMAX", Distance: 0.5},
+				{Value: "IPHONE15PROMAX", Distance: 1},
+				{Value: "IPHONE16PRO", Distance: 3.5},
+			},
+		},
+		{
+			name:  "look-alike characters and swapped characters",
+			value: "0PPOA3B-",
+			expected: []productmapper.Suggestion{
+				{Value: "OPPOA3-B", Distance: 1.5},
+				{Value: "OPPOA3", Distance: 2.5},
+			},
+		},
+		{
+			name:  "lower case",
+			value: "oppoa3",
+			expected: []productmapper.Suggestion{
+				{Value: "OPPOA3", Distance: 0},
+				{Value: "OPPOA3-B", Distance: 1.5},
+			},
+		},
+		{
+			name:  "nothing close enough",
+			value: "NOKIA3310",
+		},
+	}
+
+	suggester := productmapper.NewSuggester()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, suggester.Suggest(test.value, candidates))
+		})
+	}
+}
+
+func TestExtractorSuggestions(t *testing.T) {
+	catalog, err := productmapper.LoadModelCatalog(strings.NewReader(modelCatalogCSV))
+	assert.NoError(t, err)
+
+	extractor := productmapper.NewExtractor()
+	extractor.Textures = productmapper.DefaultTextureRegistry()
+	extractor.Models = catalog
+
+	t.Run("aliases are suggested as their model", func(t *testing.T) {
+		result, err := extractor.Parse("FG0A-CLEAR-IPHONE16PN")
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Diagnostics, 1)
+		assert.Equal(t, []string{"IPHONE16PROMAX"}, result.Diagnostics[0].Suggestions)
+	})
+
+	t.Run("suggestions are part of the strict mode error", func(t *testing.T) {
+		strict := *extractor
+		strict.Mode = productmapper.ModeStrict
+
+		_, err := strict.Parse("FG0A-PRIVAYC-OPPOA3")
+
+		assert.EqualError(t, err, "Parse Error: unknown texture id 'PRIVAYC' at index 5 in 'FG0A-PRIVAYC-OPPOA3', did you mean 'PRIVACY'?")
+	})
+
+	t.Run("no suggestions without a suggester", func(t *testing.T) {
+		withoutSuggester := *extractor
+		withoutSuggester.Suggester = nil
+
+		result, err := withoutSuggester.Parse("FG0A-MATE-OPPOA3")
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Diagnostics, 1)
+		assert.Nil(t, result.Diagnostics[0].Suggestions)
+	})
+}
@@ -0,0 +1,104 @@
+package productmapper
+
+import (
+	"errors"
+	"math"
+)
+
+const DefaultVATRate = 0.07
+
+type TaxMode int
+
+const (
+	// TaxInclusive treats the line price as gross, VAT is taken out of it.
+	TaxInclusive TaxMode = iota
+	// TaxExclusive treats the line price as net, VAT is added on top of it.
+	TaxExclusive
+)
+
+type TaxRounding int
+
+const (
+	// TaxRoundPerLine rounds the VAT of every line on its own.
+	TaxRoundPerLine TaxRounding = iota
+	// TaxRoundPerInvoice rounds the VAT of the sum of the lines and spreads it
+	// over the lines, so the line VAT adds up to the invoice VAT.
+	TaxRoundPerInvoice
+)
+
+type TaxConfig struct {
+	Rate     float64
+	Mode     TaxMode
+	Rounding TaxRounding
+}
+
+func DefaultTaxConfig() TaxConfig {
+	return TaxConfig{
+		Rate: DefaultVATRate,
+		Mode: TaxInclusive,
+	}
+}
+
+var ErrInvalidTaxRate = errors.New("invalid tax rate")
+
+// ApplyTax sets NetAmount, TaxAmount and GrossAmount of every sellable line
+// from its NetTotalPrice, rounded to 2 decimals. Component lines are not
+// taxed, their kit line carries the tax. The line price is kept exactly:
+// it is GrossAmount in TaxInclusive mode and NetAmount in TaxExclusive mode,
+// and NetAmount + TaxAmount is always GrossAmount.
+func ApplyTax(orders []CleanedOrder, config TaxConfig) ([]CleanedOrder, error) {
+	if config.Rate < 0 || math.IsNaN(config.Rate) || math.IsInf(config.Rate, 0) {
+		return nil, ErrInvalidTaxRate
+	}
+
+	taxed := make([]CleanedOrder, len(orders))
+	copy(taxed, orders)
+
+	amounts := make([]int64, len(taxed))
+	taxes := make([]int64, len(taxed))
+	var amountTotal int64
+	for i := range taxed {
+		if !taxed[i].IsSellable() {
+			continue
+		}
+		amounts[i] = toSatang(taxed[i].NetTotalPrice())
+		taxes[i] = taxOf(amounts[i], config)
+		amountTotal += amounts[i]
+	}
+
+	if config.Rounding == TaxRoundPerInvoice && amountTotal != 0 {
+		weights := make([]float64, len(amounts))
+		for i, amount := range amounts {
+			weights[i] = float64(amount)
+		}
+		for i, tax := range allocate(fromSatang(taxOf(amountTotal, config)), weights) {
+			taxes[i] = toSatang(tax)
+		}
+	}
+
+	for i := range taxed {
+		if !taxed[i].IsSellable() {
+			continue
+		}
+		switch config.Mode {
+		case TaxExclusive:
+			taxed[i].NetAmount = fromSatang(amounts[i])
+			taxed[i].TaxAmount = fromSatang(taxes[i])
+			taxed[i].GrossAmount = fromSatang(amounts[i] + taxes[i])
+		default:
+			taxed[i].NetAmount = fromSatang(amounts[i] - taxes[i])
+			taxed[i].TaxAmount = fromSatang(taxes[i])
+			taxed[i].GrossAmount = fromSatang(amounts[i])
+		}
+	}
+
+	return taxed, nil
+}
+
+// taxOf returns the VAT in satang of an amount in satang.
+func taxOf(amount int64, config TaxConfig) int64 {
+	if config.Mode == TaxExclusive {
+		return int64(math.Round(float64(amount) * config.Rate))
+	}
+	return int64(math.Round(float64(amount) * config.Rate / (1 + config.Rate)))
+}
@@ -0,0 +1,158 @@
+package productmapper_test
+
+import (
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyTax(t *testing.T) {
+	orders := []productmapper.CleanedOrder{
+		{
+			ProductId:  "FG0A-CLEAR-OPPOA3",
+			Qty:        1,
+			UnitPrice:  100,
+			TotalPrice: 100,
+		},
+		{
+			ProductId:  "FG0A-MATTE-OPPOA3",
+			Qty:        3,
+			UnitPrice:  33.33,
+			TotalPrice: 99.99,
+			Discount:   9.99,
+		},
+		{
+			ProductId: "WIPING-CLOTH",
+			Qty:       4,
+		},
+	}
+
+	type breakdown struct {
+		net, tax, gross float64
+	}
+
+	tests := []struct {
+		name     string
+		config   productmapper.TaxConfig
+		expected []breakdown
+		err      error
+	}{
+		{
+			name:   "inclusive",
+			config: productmapper.DefaultTaxConfig(),
+			expected: []breakdown{
+				{net: 93.46, tax: 6.54, gross: 100},
+				{net: 84.11, tax: 5.89, gross: 90},
+				{},
+			},
+		},
+		{
+			name:   "exclusive",
+			config: productmapper.TaxConfig{Rate: 0.07, Mode: productmapper.TaxExclusive},
+			expected: []breakdown{
+				{net: 100, tax: 7, gross: 107},
+				{net: 90, tax: 6.3, gross: 96.3},
+				{},
+			},
+		},
+		{
+			name:   "negative rate",
+			config: productmapper.TaxConfig{Rate: -0.07},
+			err:    productmapper.ErrInvalidTaxRate,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			taxed, err := productmapper.ApplyTax(orders, test.config)
+
+			assert.Equal(t, test.err, err)
+			if test.err != nil {
+				return
+			}
+
+			var actual []breakdown
+			for _, order := range taxed {
+				actual = append(actual, breakdown{net: order.NetAmount, tax: order.TaxAmount, gross: order.GrossAmount})
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+
+	t.Run("rounded per invoice", func(t *testing.T) {
+		lines := []productmapper.CleanedOrder{
+			{ProductId: "DUST-STICKER", TotalPrice: 1},
+			{ProductId: "DUST-STICKER", TotalPrice: 1},
+			{ProductId: "DUST-STICKER", TotalPrice: 1},
+		}
+
+		perLine, err := productmapper.ApplyTax(lines, productmapper.TaxConfig{Rate: 0.07})
+		assert.NoError(t, err)
+		perInvoice, err := productmapper.ApplyTax(lines, productmapper.TaxConfig{Rate: 0.07, Rounding: productmapper.TaxRoundPerInvoice})
+		assert.NoError(t, err)
+
+		var perLineTax, perInvoiceTax []float64
+		for i := range lines {
+			perLineTax = append(perLineTax, perLine[i].TaxAmount)
+			perInvoiceTax = append(perInvoiceTax, perInvoice[i].TaxAmount)
+			assert.Equal(t, 1.0, perInvoice[i].GrossAmount)
+		}
+		assert.Equal(t, []float64{0.07, 0.07, 0.07}, perLineTax)
+		assert.Equal(t, []float64{0.06, 0.07, 0.07}, perInvoiceTax)
+	})
+
+	t.Run("components of a kit are not taxed", func(t *testing.T) {
+		lines, err := productmapper.ExpandBOM([]productmapper.CleanedOrder{
+			{ProductId: "FG0A-CLEAR-KIT", Qty: 1, UnitPrice: 107, TotalPrice: 107},
+		}, []productmapper.BOM{
+			{
+				ProductId: "FG0A-CLEAR-KIT",
+				Components: []productmapper.BOMComponent{
+					{ProductId: "FG0A-CLEAR-OPPOA3", Qty: 1},
+					{ProductId: "DUST-STICKER", Qty: 1},
+				},
+			},
+		})
+		assert.NoError(t, err)
+
+		for _, rounding := range []productmapper.TaxRounding{productmapper.TaxRoundPerLine, productmapper.TaxRoundPerInvoice} {
+			taxed, err := productmapper.ApplyTax(lines, productmapper.TaxConfig{Rate: 0.07, Rounding: rounding})
+			assert.NoError(t, err)
+
+			var tax float64
+			for _, line := range taxed {
+				tax += line.TaxAmount
+			}
+			assert.Equal(t, 7.0, tax)
+			assert.Equal(t, 107.0, taxed[0].GrossAmount)
+			assert.Zero(t, taxed[1].GrossAmount)
+			assert.Zero(t, taxed[2].GrossAmount)
+		}
+	})
+
+	t.Run("net and tax add up to gross", func(t *testing.T) {
+		for _, mode := range []productmapper.TaxMode{productmapper.TaxInclusive, productmapper.TaxExclusive} {
+			var lines []productmapper.CleanedOrder
+			for satang := 1; satang <= 10000; satang += 7 {
+				lines = append(lines, productmapper.CleanedOrder{TotalPrice: float64(satang) / 100})
+			}
+
+			taxed, err := productmapper.ApplyTax(lines, productmapper.TaxConfig{Rate: 0.07, Mode: mode})
+			assert.NoError(t, err)
+
+			for i, line := range taxed {
+				assert.Equal(t, line.GrossAmount, roundSatang(line.NetAmount+line.TaxAmount), "line %d", i)
+				if mode == productmapper.TaxInclusive {
+					assert.Equal(t, lines[i].TotalPrice, line.GrossAmount, "line %d", i)
+				} else {
+					assert.Equal(t, lines[i].TotalPrice, line.NetAmount, "line %d", i)
+				}
+			}
+		}
+	})
+}
+
+func roundSatang(amount float64) float64 {
+	return float64(int64(amount*100+0.5)) / 100
+}
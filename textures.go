@@ -0,0 +1,49 @@
+package productmapper
+
+import "slices"
+
+// TextureRegistry holds the known texture ids and their aliases.
+type TextureRegistry struct {
+	textures map[string]string // texture id or alias -> texture id
+}
+
+func NewTextureRegistry() *TextureRegistry {
+	return &TextureRegistry{
+		textures: map[string]string{},
+	}
+}
+
+// DefaultTextureRegistry returns the textures we sell with the aliases sellers commonly use.
+func DefaultTextureRegistry() *TextureRegistry {
+	r := NewTextureRegistry()
+	r.Add("CLEAR")
+	r.Add("MATTE", "MATT")
+	r.Add("PRIVACY", "PRIV")
+	return r
+}
+
+// Add registers textureId and its aliases, an alias registered earlier is overwritten.
+func (r *TextureRegistry) Add(textureId string, aliases ...string) {
+	r.textures[textureId] = textureId
+	for _, alias := range aliases {
+		r.textures[alias] = textureId
+	}
+}
+
+// Resolve returns the texture id for a texture id or an alias.
+func (r *TextureRegistry) Resolve(textureId string) (string, bool) {
+	canonical, ok := r.textures[textureId]
+	return canonical, ok
+}
+
+// Textures returns the registered texture ids without aliases, sorted.
+func (r *TextureRegistry) Textures() []string {
+	var textures []string
+	for id, canonical := range r.textures {
+		if id == canonical {
+			textures = append(textures, id)
+		}
+	}
+	slices.Sort(textures)
+	return textures
+}
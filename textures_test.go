@@ -0,0 +1,126 @@
+package productmapper_test
+
+import (
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextureRegistry(t *testing.T) {
+	registry := productmapper.DefaultTextureRegistry()
+	registry.Add("HYDROGEL", "HYDRO")
+
+	tests := []struct {
+		textureId string
+		expected  string
+		ok        bool
+	}{
+		{textureId: "CLEAR", expected: "CLEAR", ok: true},
+		{textureId: "MATT", expected: "MATTE", ok: true},
+		{textureId: "PRIV", expected: "PRIVACY", ok: true},
+		{textureId: "HYDRO", expected: "HYDROGEL", ok: true},
+		{textureId: "MATE"},
+		{textureId: "CLEARR"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.textureId, func(t *testing.T) {
+			textureId, ok := registry.Resolve(test.textureId)
+
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, textureId)
+		})
+	}
+
+	assert.Equal(t, []string{"CLEAR", "HYDROGEL", "MATTE", "PRIVACY"}, registry.Textures())
+}
+
+func TestExtractorTextures(t *testing.T) {
+	tests := []struct {
+		name              string
+		mode              productmapper.Mode
+		platformProductId string
+		expected          *productmapper.ParseResult
+		err               error
+	}{
+		{
+			name:              "aliases are mapped to texture id",
+			platformProductId: "FG0A-MATT-OPPOA3/FG0A-PRIV-OPPOA3*2",
+			expected: &productmapper.ParseResult{
+				Products: []productmapper.ProductParts{
+					{
+						FilmTypeId: "FG0A",
+						TextureId:  "MATTE",
+						ModelId:    "OPPOA3",
+						Qty:        1,
+					},
+					{
+						FilmTypeId: "FG0A",
+						TextureId:  "PRIVACY",
+						ModelId:    "OPPOA3",
+						Qty:        2,
+					},
+				},
+				TotalQty: 3,
+			},
+		},
+		{
+			name:              "unknown texture is a warning in lenient mode",
+			mode:              productmapper.ModeLenient,
+			platformProductId: "FG0A-CLEAR-OPPOA3/FI2A-MATE-NOKIA3310",
+			expected: &productmapper.ParseResult{
+				Products: []productmapper.ProductParts{
+					{
+						FilmTypeId: "FG0A",
+						TextureId:  "CLEAR",
+						ModelId:    "OPPOA3",
+						Qty:        1,
+					},
+					{
+						FilmTypeId: "FI2A",
+						TextureId:  "MATE",
+						ModelId:    "NOKIA3310",
+						Qty:        1,
+					},
+				},
+				TotalQty: 2,
+				Diagnostics: []productmapper.Diagnostic{
+					{
+						Code:        productmapper.DiagnosticUnknownTexture,
+						Message:     "unknown texture id 'MATE'",
+						Input:       "FG0A-CLEAR-OPPOA3/FI2A-MATE-NOKIA3310",
+						Index:       23,
+						Value:       "MATE",
+						Suggestions: []string{"MATTE"},
+					},
+				},
+			},
+		},
+		{
+			name:              "unknown texture is an error in strict mode",
+			mode:              productmapper.ModeStrict,
+			platformProductId: "FG0A-CLEARR-OPPOA3",
+			err: &productmapper.ParseError{
+				Message:     "unknown texture id 'CLEARR'",
+				Input:       "FG0A-CLEARR-OPPOA3",
+				Index:       5,
+				Suggestions: []string{"CLEAR"},
+				Code:        productmapper.DiagnosticUnknownTexture,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			extractor := productmapper.NewExtractor()
+			extractor.Textures = productmapper.DefaultTextureRegistry()
+			extractor.Mode = test.mode
+
+			result, err := extractor.Parse(test.platformProductId)
+
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package productmapper
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	AdjustmentDiscount = "DISCOUNT"
+	AdjustmentFee      = "FEE"
+)
+
+// PriceTrace explains how the price of a cleaned line was allocated from its
+// marketplace line. It is only recorded in explain mode, see CleanOrderExplained.
+type PriceTrace struct {
+	// LineQty, LineUnitPrice and LineTotalPrice are the InputOrder amounts.
+	LineQty        int     `json:"line_qty"`
+	LineUnitPrice  float64 `json:"line_unit_price"`
+	LineTotalPrice float64 `json:"line_total_price"`
+	// AllocatedTotal is the line price that was diffused, see Diffuser.Trust.
+	AllocatedTotal float64 `json:"allocated_total"`
+	// TotalQty is the number of products in one line item, PaidQty the number
+	// of units of the line sharing AllocatedTotal.
+	TotalQty int `json:"total_qty"`
+	PaidQty  int `json:"paid_qty"`
+	// Weight is the share of AllocatedTotal the line took.
+	Weight float64 `json:"weight"`
+	Free   bool    `json:"free,omitempty"`
+	// Floor is the minimum unit price of the line, see Diffuser.Floors.
+	Floor       float64           `json:"floor,omitempty"`
+	Adjustments []TraceAdjustment `json:"adjustments,omitempty"`
+}
+
+// TraceAdjustment is an order level amount allocated to the line.
+type TraceAdjustment struct {
+	Kind string `json:"kind"` // DISCOUNT, FEE
+	Type string `json:"type"`
+	// Weight is the share of the order level amount the line took, Share the
+	// unrounded amount and Amount the amount added to the line. Rounding is
+	// Amount less Share.
+	Weight   float64 `json:"weight"`
+	Share    float64 `json:"share"`
+	Amount   float64 `json:"amount"`
+	Rounding float64 `json:"rounding"`
+}
+
+// withAdjustment returns a copy of t with adjustment appended, traces are
+// shared by the copies of a line so they are never changed in place.
+func (t *PriceTrace) withAdjustment(adjustment TraceAdjustment) *PriceTrace {
+	trace := *t
+	trace.Adjustments = append(slices.Clip(t.Adjustments), adjustment)
+	return &trace
+}
+
+// traceAdjustment explains the amount line j took of an order level total
+// allocated by weights.
+func traceAdjustment(kind string, adjustmentType string, total float64, weights []float64, j int, amount float64) TraceAdjustment {
+	var sum float64
+	for _, weight := range weights {
+		sum += weight
+	}
+	weight := 1 / float64(len(weights))
+	if sum != 0 {
+		weight = weights[j] / sum
+	}
+
+	return TraceAdjustment{
+		Kind:     kind,
+		Type:     adjustmentType,
+		Weight:   roundTrace(weight),
+		Share:    roundTrace(total * weight),
+		Amount:   amount,
+		Rounding: roundTrace(amount - total*weight),
+	}
+}
+
+func (t *PriceTrace) String() string {
+	var b strings.Builder
+	b.WriteString("line " + strconv.Itoa(t.LineQty) + " x " + formatTraceAmount(t.LineUnitPrice) + ", total " + formatTraceAmount(t.LineTotalPrice))
+	if t.AllocatedTotal != t.LineTotalPrice {
+		b.WriteString(", allocated " + formatTraceAmount(t.AllocatedTotal))
+	}
+	b.WriteString("\n")
+	b.WriteString(strconv.Itoa(t.TotalQty) + " products per item, " + strconv.Itoa(t.PaidQty) + " paid units, weight " + formatTraceAmount(t.Weight) + "\n")
+	if t.Free {
+		b.WriteString("free\n")
+	}
+	if t.Floor != 0 {
+		b.WriteString("floor " + formatTraceAmount(t.Floor) + "\n")
+	}
+	for _, adjustment := range t.Adjustments {
+		b.WriteString(strings.ToLower(adjustment.Kind) + " " + adjustment.Type + " " + formatTraceAmount(adjustment.Amount) +
+			", weight " + formatTraceAmount(adjustment.Weight) +
+			", share " + formatTraceAmount(adjustment.Share) +
+			", rounding " + formatTraceAmount(adjustment.Rounding) + "\n")
+	}
+	return b.String()
+}
+
+func formatTraceAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// roundTrace rounds the computed amounts of a trace to 6 decimals, enough to
+// show the rounding of 2 decimal amounts.
+func roundTrace(amount float64) float64 {
+	return math.Round(amount*1e6) / 1e6
+}
@@ -0,0 +1,89 @@
+package productmapper_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanOrderExplained(t *testing.T) {
+	orders, err := productmapper.CleanOrderExplained(context.Background(), []productmapper.InputOrder{
+		{
+			No:                1,
+			PlatformProductId: "FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3*2",
+			Qty:               1,
+			UnitPrice:         100,
+			TotalPrice:        100,
+		},
+	}, []productmapper.ComplementaryItem{
+		{
+			ProductId: "WIPING-CLOTH",
+			PerQty:    1,
+		},
+	})
+	assert.NoError(t, err)
+
+	discounted, err := productmapper.ProrateDiscounts(orders, productmapper.OrderHeader{
+		Discounts: []productmapper.OrderDiscount{
+			{Type: productmapper.DiscountShopVoucher, Amount: 10},
+		},
+	}, productmapper.ProrateByValue)
+	assert.NoError(t, err)
+
+	t.Run("text", func(t *testing.T) {
+		assert.Equal(t, "line 1 x 100, total 100\n"+
+			"3 products per item, 3 paid units, weight 0.333333\n"+
+			"discount SHOP_VOUCHER 3.33, weight 0.333333, share 3.333333, rounding -0.003333\n", discounted[0].Trace.String())
+		assert.Equal(t, "line 1 x 100, total 100\n"+
+			"3 products per item, 3 paid units, weight 0.666667\n"+
+			"discount SHOP_VOUCHER 6.67, weight 0.666667, share 6.666667, rounding 0.003333\n", discounted[1].Trace.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		trace, err := json.Marshal(discounted[0].Trace)
+
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"line_qty": 1,
+			"line_unit_price": 100,
+			"line_total_price": 100,
+			"allocated_total": 100,
+			"total_qty": 3,
+			"paid_qty": 3,
+			"weight": 0.333333,
+			"adjustments": [
+				{
+					"kind": "DISCOUNT",
+					"type": "SHOP_VOUCHER",
+					"weight": 0.333333,
+					"share": 3.333333,
+					"amount": 3.33,
+					"rounding": -0.003333
+				}
+			]
+		}`, string(trace))
+	})
+
+	t.Run("complementary items and input orders", func(t *testing.T) {
+		assert.Nil(t, discounted[2].Trace)
+		assert.Empty(t, orders[0].Trace.Adjustments, "input traces are not modified")
+	})
+
+	t.Run("not recorded by CleanOrder", func(t *testing.T) {
+		orders, err := productmapper.CleanOrder(context.Background(), []productmapper.InputOrder{
+			{
+				No:                1,
+				PlatformProductId: "FG0A-CLEAR-OPPOA3",
+				Qty:               1,
+				UnitPrice:         100,
+				TotalPrice:        100,
+			},
+		}, nil)
+
+		assert.NoError(t, err)
+		assert.Nil(t, orders[0].Trace)
+	})
+}
@@ -0,0 +1,138 @@
+package productmapper
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Span names of Mapper.Clean. A stage span is named after the stage, an
+// order span is the work on one InputOrder within the extract and diffuse stages.
+const (
+	SpanClean         = "productmapper.Clean"
+	SpanOrder         = "productmapper.order"
+	SpanExtract       = "productmapper." + StageExtract
+	SpanDiffuse       = "productmapper." + StageDiffuse
+	SpanProrate       = "productmapper." + StageProrate
+	SpanComplementary = "productmapper." + StageComplementary
+	SpanCatalog       = "productmapper." + StageCatalog
+)
+
+func spanName(stage string) string {
+	return "productmapper." + stage
+}
+
+// Tracer starts spans around the stages of Mapper.Clean, see WithTracer. It
+// follows the OpenTelemetry API so an adapter is a few lines: the returned
+// context carries the span and is the parent of the spans started from it.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttributes(attrs ...slog.Attr)
+	RecordError(err error)
+	End()
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ ...slog.Attr) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...slog.Attr) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
+
+// RecordedSpan is a span kept by a SpanRecorder.
+type RecordedSpan struct {
+	// Id is the position of the span in SpanRecorder.Spans, ParentId is -1
+	// for root spans.
+	Id       int
+	ParentId int
+	Name     string
+	Attrs    []slog.Attr
+	Err      error
+	Start    time.Time
+	End      time.Time
+	Ended    bool
+}
+
+// SpanRecorder is a Tracer that keeps the spans in memory, for tests and debugging.
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+}
+
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+type recordedSpanKey struct{}
+
+func (r *SpanRecorder) Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span) {
+	parentId := -1
+	if parent, ok := ctx.Value(recordedSpanKey{}).(*recordingSpan); ok && parent.recorder == r {
+		parentId = parent.id
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	span := &recordingSpan{recorder: r, id: len(r.spans)}
+	r.spans = append(r.spans, RecordedSpan{
+		Id:       span.id,
+		ParentId: parentId,
+		Name:     name,
+		Attrs:    slices.Clone(attrs),
+		Start:    time.Now(),
+	})
+	return context.WithValue(ctx, recordedSpanKey{}, span), span
+}
+
+// Spans returns the recorded spans in the order they were started.
+func (r *SpanRecorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	spans := slices.Clone(r.spans)
+	for i := range spans {
+		spans[i].Attrs = slices.Clone(spans[i].Attrs)
+	}
+	return spans
+}
+
+type recordingSpan struct {
+	recorder *SpanRecorder
+	id       int
+}
+
+func (s *recordingSpan) SetAttributes(attrs ...slog.Attr) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.spans[s.id].Attrs = append(s.recorder.spans[s.id].Attrs, attrs...)
+}
+
+func (s *recordingSpan) RecordError(err error) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.spans[s.id].Err = err
+}
+
+func (s *recordingSpan) End() {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+	s.recorder.spans[s.id].End = time.Now()
+	s.recorder.spans[s.id].Ended = true
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,82 @@
+package productmapper_test
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Kritsana135/productmapper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapperTracing(t *testing.T) {
+	type span struct {
+		name   string
+		parent int
+		attrs  []slog.Attr
+		err    bool
+	}
+	recorded := func(recorder *productmapper.SpanRecorder) []span {
+		var spans []span
+		for _, s := range recorder.Spans() {
+			assert.True(t, s.Ended, s.Name)
+			assert.False(t, s.End.Before(s.Start), s.Name)
+			spans = append(spans, span{name: s.Name, parent: s.ParentId, attrs: s.Attrs, err: s.Err != nil})
+		}
+		return spans
+	}
+
+	t.Run("spans per stage and order", func(t *testing.T) {
+		recorder := productmapper.NewSpanRecorder()
+		catalog, err := productmapper.LoadCatalog(strings.NewReader(catalogCSV))
+		assert.NoError(t, err)
+
+		ctx, parent := recorder.Start(context.Background(), "handler")
+		_, err = productmapper.NewMapper(
+			productmapper.WithTracer(recorder),
+			productmapper.WithCatalog(catalog),
+			productmapper.WithComplementaryItems(productmapper.ComplementaryItem{ProductId: "WIPING-CLOTH", PerQty: 1}),
+		).Clean(ctx, []productmapper.InputOrder{
+			{No: 1, PlatformProductId: "FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3", Qty: 1, UnitPrice: 100, TotalPrice: 100},
+			{No: 2, PlatformProductId: "FG0A-MATTE-OPPOA3", Qty: 1, UnitPrice: 50, TotalPrice: 50},
+		})
+		parent.End()
+
+		order1 := []slog.Attr{slog.Int("order_no", 1), slog.String("platform_product_id", "FG0A-CLEAR-OPPOA3/FG0A-MATTE-OPPOA3")}
+		order2 := []slog.Attr{slog.Int("order_no", 2), slog.String("platform_product_id", "FG0A-MATTE-OPPOA3")}
+		assert.NoError(t, err)
+		assert.Equal(t, []span{
+			{name: "handler", parent: -1},
+			{name: productmapper.SpanClean, parent: 0, attrs: []slog.Attr{slog.Int("orders", 2)}},
+			{name: productmapper.SpanExtract, parent: 1},
+			{name: productmapper.SpanOrder, parent: 2, attrs: append(slices.Clone(order1), slog.Int("products", 2), slog.Int("diagnostics", 0))},
+			{name: productmapper.SpanOrder, parent: 2, attrs: append(slices.Clone(order2), slog.Int("products", 1), slog.Int("diagnostics", 0))},
+			{name: productmapper.SpanDiffuse, parent: 1},
+			{name: productmapper.SpanOrder, parent: 5, attrs: append(slices.Clone(order1), slog.Int("lines", 2))},
+			{name: productmapper.SpanOrder, parent: 5, attrs: append(slices.Clone(order2), slog.Int("lines", 1))},
+			{name: productmapper.SpanProrate, parent: 1},
+			{name: productmapper.SpanComplementary, parent: 1},
+			{name: productmapper.SpanCatalog, parent: 1},
+		}, recorded(recorder))
+	})
+
+	t.Run("errors are recorded on the failing spans", func(t *testing.T) {
+		recorder := productmapper.NewSpanRecorder()
+
+		_, err := productmapper.NewMapper(productmapper.WithTracer(recorder)).Clean(context.Background(), []productmapper.InputOrder{
+			{No: 1, PlatformProductId: "FG0A-CLEAR-OPPOA3*", Qty: 1, UnitPrice: 100, TotalPrice: 100},
+		})
+
+		assert.Error(t, err)
+		assert.Equal(t, []span{
+			{name: productmapper.SpanClean, parent: -1, attrs: []slog.Attr{slog.Int("orders", 1)}, err: true},
+			{name: productmapper.SpanExtract, parent: 0, err: true},
+			{name: productmapper.SpanOrder, parent: 1, attrs: []slog.Attr{
+				slog.Int("order_no", 1),
+				slog.String("platform_product_id", "FG0A-CLEAR-OPPOA3*"),
+			}, err: true},
+		}, recorded(recorder))
+	})
+}